@@ -2,17 +2,65 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"grade-management-system/logging"
+	"log/slog"
 	"os"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+// Supported values of DB_DRIVER
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// SQLiteMemory is the DB_PATH that keeps a SQLite database in memory
+const SQLiteMemory = ":memory:"
+
+// memoryDBs counts the in-memory databases opened, to name them
+var memoryDBs atomic.Int64
+
 func ConnectDatabase() {
-	// Read database config from environment variables
+	driver := os.Getenv("DB_DRIVER")
+	if driver == "" {
+		driver = DriverPostgres
+	}
+
+	var (
+		database *gorm.DB
+		err      error
+	)
+	switch driver {
+	case DriverPostgres:
+		database, err = OpenPostgres(postgresDSN())
+	case DriverSQLite:
+		path := os.Getenv("DB_PATH")
+		if path == "" {
+			path = "grade_management.db"
+		}
+		database, err = OpenSQLite(path)
+	default:
+		logging.Fatal("Unknown DB_DRIVER, expected "+DriverPostgres+" or "+DriverSQLite, "driver", driver)
+	}
+	if err != nil {
+		logging.Fatal("Failed to connect to database", "error", err)
+	}
+
+	slog.Info("Database connection established", "driver", driver)
+	DB = database
+}
+
+// postgresDSN builds the Postgres connection string from the DB_* variables
+func postgresDSN() string {
 	host := os.Getenv("DB_HOST")
 	user := os.Getenv("DB_USER")
 	password := os.Getenv("DB_PASSWORD")
@@ -24,13 +72,65 @@ func ConnectDatabase() {
 		sslmode = "disable"
 	}
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s", host, user, password, dbname, port, sslmode)
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s", host, user, password, dbname, port, sslmode)
+}
+
+// gormConfig logs slow queries and database errors through the default slog
+// logger. Queries are logged with placeholders, never with their arguments,
+// which can hold password hashes and tokens.
+func gormConfig() *gorm.Config {
+	return &gorm.Config{
+		Logger: logger.NewSlogLogger(slog.Default(), logger.Config{
+			SlowThreshold:             200 * time.Millisecond,
+			LogLevel:                  logger.Warn,
+			IgnoreRecordNotFoundError: true,
+			ParameterizedQueries:      true,
+		}),
+	}
+}
+
+// OpenPostgres connects to a Postgres server
+func OpenPostgres(dsn string) (*gorm.DB, error) {
+	return gorm.Open(postgres.Open(dsn), gormConfig())
+}
+
+// OpenSQLite opens a SQLite database file, creating it if needed, or a private
+// in-memory database when path is SQLiteMemory. Foreign keys are enforced so
+// cascades and restrictions behave as they do on Postgres.
+func OpenSQLite(path string) (*gorm.DB, error) {
+	pragmas := "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+
+	var dsn string
+	if path == SQLiteMemory {
+		// A new name per call keeps separate databases apart, e.g. in tests
+		dsn = fmt.Sprintf("file:memdb-%d?mode=memory&%s", memoryDBs.Add(1), pragmas)
+	} else {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		dsn = path + separator + pragmas + "&_pragma=journal_mode(WAL)"
+	}
 
-	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	database, err := gorm.Open(sqlite.Open(dsn), gormConfig())
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		return nil, err
 	}
 
-	log.Println("Database connection established!")
-	DB = database
+	if path == SQLiteMemory {
+		// An in-memory database belongs to the connection that opened it and
+		// lives as long as that connection. Sharing it between connections
+		// through SQLite's shared cache would fail concurrent writers with
+		// SQLITE_LOCKED instead of letting them wait, so every query goes
+		// through one connection that is never closed.
+		sqlDB, err := database.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+	}
+	return database, nil
 }
@@ -3,12 +3,13 @@ package controllers
 import (
 	"grade-management-system/config"
 	"grade-management-system/models"
+	"grade-management-system/services"
 	"grade-management-system/utils"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // HealthCheck endpoint to verify server availability
@@ -18,42 +19,26 @@ func HealthCheck(c *gin.Context) {
 	})
 }
 
-// CreateUser allowed only for Admins to create Teachers or Students
+// CreateUser allowed only for Admins to create Teachers, Students or Guardians
 type CreateUserInput struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
-	Role     string `json:"role" binding:"required,oneof=teacher student"`
+	Role     string `json:"role" binding:"required,oneof=teacher student guardian"`
 }
 
-func CreateUser(c *gin.Context) {
+func (h *Handlers) CreateUser(c *gin.Context) {
 	var input CreateUserInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	var existingUser models.User
-	if err := config.DB.Where("email = ?", input.Email).First(&existingUser).Error; err == nil {
-		utils.ErrorResponse(c, http.StatusConflict, "Email already in use")
-		return
-	}
-
-	hashedPassword, err := utils.HashPassword(input.Password)
+	user, err := h.Users.Create(c.Request.Context(), services.NewUser{
+		Name: input.Name, Email: input.Email, Password: input.Password, Role: input.Role,
+	})
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to hash password")
-		return
-	}
-
-	user := models.User{
-		Name:     input.Name,
-		Email:    strings.ToLower(input.Email),
-		Password: hashedPassword,
-		Role:     input.Role,
-	}
-
-	if err := config.DB.Create(&user).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create user")
+		respondWithServiceError(c, err, "Failed to create user")
 		return
 	}
 
@@ -61,66 +46,149 @@ func CreateUser(c *gin.Context) {
 }
 
 type CreateCourseInput struct {
-	Name      string `json:"name" binding:"required"`
-	TeacherID uint   `json:"teacher_id" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Term        string `json:"term" binding:"max=50"`
+	Department  string `json:"department" binding:"max=100"`
+	TeacherID   uint   `json:"teacher_id" binding:"required"`
 }
 
-func CreateCourse(c *gin.Context) {
+func (h *Handlers) CreateCourse(c *gin.Context) {
 	var input CreateCourseInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Verify teacher exists and is actually a teacher
-	var teacher models.User
-	if err := config.DB.First(&teacher, input.TeacherID).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Teacher not found")
-		return
-	}
-	if teacher.Role != "teacher" {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Assigned user is not a teacher")
-		return
-	}
-
 	course := models.Course{
-		Name:      input.Name,
-		TeacherID: input.TeacherID,
+		Name:        input.Name,
+		Description: input.Description,
+		Term:        input.Term,
+		Department:  input.Department,
+		TeacherID:   input.TeacherID,
 	}
 
-	if err := config.DB.Create(&course).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create course. Ensure course name is unique.")
+	if err := h.Courses.Create(c.Request.Context(), &course); err != nil {
+		respondWithServiceError(c, err, "Failed to create course")
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusCreated, "Course created successfully", course)
 }
 
-// ListStudents returns all students with basic pagination
+// Sort keys accepted by the user and course lists, mapped to their columns
+var (
+	userSortColumns = map[string]string{
+		"id": "users.id", "name": "users.name", "email": "users.email",
+		"created_at": "users.created_at", "roll_number": "users.roll_number", "department": "users.department",
+	}
+	courseSortColumns = map[string]string{
+		"id": "courses.id", "name": "courses.name", "term": "courses.term",
+		"department": "courses.department", "created_at": "courses.created_at",
+	}
+)
+
+// ListStudents returns a page of students. It accepts the same search, filters
+// and sorting as ListUsers.
 func ListStudents(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	listUsers(c, config.DB.WithContext(c.Request.Context()).Model(&models.User{}).Where("users.role = ?", models.RoleStudent), "Students fetched successfully")
+}
+
+// ListUsers returns a page of user accounts, optionally filtered by ?role=
+func ListUsers(c *gin.Context) {
+	query := config.DB.WithContext(c.Request.Context()).Model(&models.User{})
+	if role := c.Query("role"); role != "" {
+		query = query.Where("users.role = ?", role)
+	}
+	listUsers(c, query, "Users fetched successfully")
+}
+
+// listUsers applies ?q= (name, email or roll number), ?department=,
+// ?status=active|deactivated and the pagination parameters to a user query
+func listUsers(c *gin.Context, query *gorm.DB, message string) {
+	opts, err := utils.ParseListOptions(c, userSortColumns, "id")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if opts.Search != "" {
+		pattern := utils.LikePattern(opts.Search)
+		query = query.Where(`(LOWER(users.name) LIKE ? ESCAPE '\' OR LOWER(users.email) LIKE ? ESCAPE '\' OR LOWER(users.roll_number) LIKE ? ESCAPE '\')`, pattern, pattern, pattern)
+	}
+	if department := c.Query("department"); department != "" {
+		query = query.Where("users.department = ?", department)
+	}
+	switch c.Query("status") {
+	case "":
+	case "active":
+		query = query.Where("users.deactivated_at IS NULL")
+	case "deactivated":
+		query = query.Where("users.deactivated_at IS NOT NULL")
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "status must be active or deactivated")
+		return
+	}
 
-	var students []models.User
-	if err := config.DB.Where("role = ?", "student").Limit(limit).Offset(offset).Find(&students).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch students")
+	users, page, err := utils.Paginate(c, query, opts, "users.id", func(user models.User) uint { return user.ID })
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch users")
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Students fetched successfully", students)
+	utils.PaginatedResponse(c, message, users, page)
 }
 
-// ListCourses returns all courses with basic pagination
+// ListCourses returns a page of all courses with their teacher. Besides the
+// filters of listCourses it accepts ?teacher_id=.
 func ListCourses(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	query := config.DB.WithContext(c.Request.Context()).Model(&models.Course{}).Preload("Teacher")
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		id, err := strconv.ParseUint(teacherID, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "teacher_id must be a number")
+			return
+		}
+		query = query.Where("courses.teacher_id = ?", id)
+	}
+
+	listCourses(c, query)
+}
 
-	var courses []models.Course
-	// Preload Teacher to show who teaches it
-	if err := config.DB.Preload("Teacher").Limit(limit).Offset(offset).Find(&courses).Error; err != nil {
+// listCourses applies ?q= (course name), ?term=, ?department=,
+// ?archived=true|false and the pagination parameters to a course query
+func listCourses(c *gin.Context, query *gorm.DB) {
+	opts, err := utils.ParseListOptions(c, courseSortColumns, "id")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if opts.Search != "" {
+		query = query.Where(`LOWER(courses.name) LIKE ? ESCAPE '\'`, utils.LikePattern(opts.Search))
+	}
+	if term := c.Query("term"); term != "" {
+		query = query.Where("courses.term = ?", term)
+	}
+	if department := c.Query("department"); department != "" {
+		query = query.Where("courses.department = ?", department)
+	}
+	switch c.Query("archived") {
+	case "":
+	case "true":
+		query = query.Where("courses.archived_at IS NOT NULL")
+	case "false":
+		query = query.Where("courses.archived_at IS NULL")
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "archived must be true or false")
+		return
+	}
+
+	courses, page, err := utils.Paginate(c, query, opts, "courses.id", func(course models.Course) uint { return course.ID })
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch courses")
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Courses fetched successfully", courses)
+	utils.PaginatedResponse(c, "Courses fetched successfully", courses, page)
 }
@@ -0,0 +1,360 @@
+package controllers
+
+import (
+	"fmt"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/repository"
+	"grade-management-system/services"
+	"grade-management-system/utils"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Institution-wide analytics for admins. Every figure is a SQL aggregate, and
+// responses are cached in memory for ANALYTICS_CACHE_TTL (default 5m, 0
+// disables caching) so dashboards refreshing often don't rescan the grades.
+
+var (
+	analyticsCache     *utils.TTLCache
+	analyticsCacheOnce sync.Once
+)
+
+// analyticsCacheEntries caps the cached responses. Keys only hold validated
+// parameters, but every term and department combination is a distinct key.
+const analyticsCacheEntries = 1000
+
+// respondWithAnalytics serves the result of compute from the cache when fresh,
+// otherwise computes and caches it. The cache key is the route and the parsed
+// params the report depends on, so each combination of filters is cached
+// separately and unknown query parameters cannot add entries.
+func respondWithAnalytics(c *gin.Context, message string, params url.Values, compute func() (interface{}, error)) {
+	analyticsCacheOnce.Do(func() {
+		ttl := 5 * time.Minute
+		if value := os.Getenv("ANALYTICS_CACHE_TTL"); value != "" {
+			if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+				ttl = parsed
+			} else {
+				slog.Warn("Invalid ANALYTICS_CACHE_TTL, using 5m", "value", value)
+			}
+		}
+		analyticsCache = utils.NewTTLCache(ttl, analyticsCacheEntries)
+	})
+
+	ttl := analyticsCache.TTL()
+	if ttl <= 0 {
+		c.Header("Cache-Control", "no-store")
+	} else {
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int(ttl.Seconds())))
+	}
+
+	key := c.FullPath() + "?" + params.Encode()
+	if data, ok := analyticsCache.Get(key); ok && ttl > 0 {
+		c.Header("X-Cache", "HIT")
+		utils.SuccessResponse(c, http.StatusOK, message, data)
+		return
+	}
+
+	data, err := compute()
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Analytics failed", "key", key, "error", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to compute analytics")
+		return
+	}
+	if ttl > 0 {
+		analyticsCache.Set(key, data)
+	}
+	c.Header("X-Cache", "MISS")
+	utils.SuccessResponse(c, http.StatusOK, message, data)
+}
+
+// analyticsFilters are the ?term= and ?department= filters of a report
+type analyticsFilters struct {
+	Term       string
+	Department string
+}
+
+// parseAnalyticsFilters reads the filters, responding with 400 when one is
+// longer than the course column it matches can hold
+func parseAnalyticsFilters(c *gin.Context) (analyticsFilters, bool) {
+	filters := analyticsFilters{Term: c.Query("term"), Department: c.Query("department")}
+	if len(filters.Term) > 50 || len(filters.Department) > 100 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "term is limited to 50 characters and department to 100")
+		return filters, false
+	}
+	return filters, true
+}
+
+// values returns the filters as cache key params
+func (f analyticsFilters) values() url.Values {
+	return url.Values{"term": {f.Term}, "department": {f.Department}}
+}
+
+// apply restricts a query joined with courses to the filters
+func (f analyticsFilters) apply(query *gorm.DB) *gorm.DB {
+	if f.Term != "" {
+		query = query.Where("courses.term = ?", f.Term)
+	}
+	if f.Department != "" {
+		query = query.Where("courses.department = ?", f.Department)
+	}
+	return query
+}
+
+// EnrollmentCount counts the enrollments of a course, department or term
+type EnrollmentCount struct {
+	Group       string `json:"group"` // Course name, department or term
+	CourseID    uint   `json:"course_id,omitempty"`
+	Enrollments int64  `json:"enrollments"`
+	Graded      int64  `json:"graded"`
+	Ungraded    int64  `json:"ungraded"`
+}
+
+// EnrollmentAnalytics counts enrollments and graded enrollments per course,
+// department or term (?group_by=course|department|term)
+func EnrollmentAnalytics(c *gin.Context) {
+	filters, ok := parseAnalyticsFilters(c)
+	if !ok {
+		return
+	}
+	groupBy := c.DefaultQuery("group_by", "course")
+	var columns, group string
+	switch groupBy {
+	case "course":
+		columns, group = "courses.name AS \"group\", courses.id AS course_id", "courses.id, courses.name"
+	case "department":
+		columns, group = "courses.department AS \"group\"", "courses.department"
+	case "term":
+		columns, group = "courses.term AS \"group\"", "courses.term"
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "group_by must be course, department or term")
+		return
+	}
+
+	params := filters.values()
+	params.Set("group_by", groupBy)
+	respondWithAnalytics(c, "Enrollment counts computed", params, func() (interface{}, error) {
+		counts := []EnrollmentCount{}
+		query := config.DB.WithContext(c.Request.Context()).Table("enrollments").
+			Select(columns + ", COUNT(enrollments.id) AS enrollments, COUNT(grades.id) AS graded").
+			Joins("JOIN courses ON courses.id = enrollments.course_id").
+			Joins("LEFT JOIN grades ON grades.student_id = enrollments.student_id AND grades.course_id = enrollments.course_id").
+			Group(group).
+			Order("COUNT(enrollments.id) DESC")
+		if err := filters.apply(query).Scan(&counts).Error; err != nil {
+			return nil, err
+		}
+		for i := range counts {
+			counts[i].Ungraded = counts[i].Enrollments - counts[i].Graded
+		}
+		return counts, nil
+	})
+}
+
+// CourseGradeDistribution is the spread of final grades in one course
+type CourseGradeDistribution struct {
+	CourseID   uint     `json:"course_id"`
+	CourseName string   `json:"course_name"`
+	Term       string   `json:"term"`
+	Department string   `json:"department"`
+	Graded     int64    `json:"graded"`
+	Average    *float64 `json:"average"`
+	A          int64    `json:"a"`
+	B          int64    `json:"b"`
+	C          int64    `json:"c"`
+	D          int64    `json:"d"`
+	F          int64    `json:"f"`
+	Passed     int64    `json:"passed"`
+	Failed     int64    `json:"failed"`
+	PassRate   *float64 `json:"pass_rate"`
+}
+
+// GradeDistributionAnalytics returns the grade letters, average and pass/fail
+// rate of every course with grades, and the same figures across all of them
+func GradeDistributionAnalytics(c *gin.Context) {
+	filters, ok := parseAnalyticsFilters(c)
+	if !ok {
+		return
+	}
+	respondWithAnalytics(c, "Grade distribution computed", filters.values(), func() (interface{}, error) {
+		courses := []CourseGradeDistribution{}
+		query := config.DB.WithContext(c.Request.Context()).Table("grades").
+			Select(`courses.id AS course_id, courses.name AS course_name, courses.term, courses.department,
+				COUNT(grades.id) AS graded, AVG(grades.marks) AS average,
+				SUM(CASE WHEN grades.grade_letter = 'A' THEN 1 ELSE 0 END) AS a,
+				SUM(CASE WHEN grades.grade_letter = 'B' THEN 1 ELSE 0 END) AS b,
+				SUM(CASE WHEN grades.grade_letter = 'C' THEN 1 ELSE 0 END) AS c,
+				SUM(CASE WHEN grades.grade_letter = 'D' THEN 1 ELSE 0 END) AS d,
+				SUM(CASE WHEN grades.grade_letter = 'F' THEN 1 ELSE 0 END) AS f,
+				SUM(CASE WHEN grades.marks >= ? THEN 1 ELSE 0 END) AS passed`, services.PassMark).
+			Joins("JOIN courses ON courses.id = grades.course_id").
+			Group("courses.id, courses.name, courses.term, courses.department").
+			Order("courses.term, courses.name")
+		if err := filters.apply(query).Scan(&courses).Error; err != nil {
+			return nil, err
+		}
+
+		// Totals are summed from the per-course rows instead of scanning again
+		total := CourseGradeDistribution{CourseName: "All courses"}
+		var marksSum float64
+		for i := range courses {
+			course := &courses[i]
+			course.Failed = course.Graded - course.Passed
+			if course.Graded > 0 {
+				course.PassRate = roundedPtr(float64(course.Passed) * 100 / float64(course.Graded))
+				marksSum += *course.Average * float64(course.Graded)
+				course.Average = roundedPtr(*course.Average)
+			}
+			total.Graded += course.Graded
+			total.A, total.B, total.C, total.D, total.F = total.A+course.A, total.B+course.B, total.C+course.C, total.D+course.D, total.F+course.F
+			total.Passed += course.Passed
+			total.Failed += course.Failed
+		}
+		if total.Graded > 0 {
+			total.Average = roundedPtr(marksSum / float64(total.Graded))
+			total.PassRate = roundedPtr(float64(total.Passed) * 100 / float64(total.Graded))
+		}
+
+		return gin.H{"overall": total, "courses": courses}, nil
+	})
+}
+
+// GPADistributionAnalytics describes the GPA of the student body, over the
+// grades matching ?term= and ?department=. Students without grades count as ungraded.
+func GPADistributionAnalytics(c *gin.Context) {
+	filters, ok := parseAnalyticsFilters(c)
+	if !ok {
+		return
+	}
+	respondWithAnalytics(c, "GPA distribution computed", filters.values(), func() (interface{}, error) {
+		var students int64
+		if err := config.DB.WithContext(c.Request.Context()).Model(&models.User{}).Where("role = ? AND deactivated_at IS NULL", models.RoleStudent).Count(&students).Error; err != nil {
+			return nil, err
+		}
+
+		gpas := config.DB.WithContext(c.Request.Context()).Table("grades").
+			Select("AVG(" + services.GradePointsSQL + ") AS value").
+			Joins("JOIN courses ON courses.id = grades.course_id").
+			Group("grades.student_id")
+		// Bins of half a grade point
+		options := repository.SummaryOptions{Max: 4, Bins: 8}
+		summary, err := repository.SummarizeValues(filters.apply(gpas), options)
+		if err != nil {
+			return nil, err
+		}
+		stats := services.NewMarkStatistics(summary, students, options)
+		// No pass mark applies to a GPA
+		stats.PassRate = nil
+		return stats, nil
+	})
+}
+
+// OutstandingGrading is a teacher with enrollments still waiting for a final grade
+type OutstandingGrading struct {
+	TeacherID uint   `json:"teacher_id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Courses   int64  `json:"courses"`  // Courses with ungraded enrollments
+	Ungraded  int64  `json:"ungraded"` // Enrollments without a grade
+}
+
+// OutstandingGradingAnalytics lists the teachers of active courses that still
+// have ungraded enrollments, most outstanding first
+func OutstandingGradingAnalytics(c *gin.Context) {
+	filters, ok := parseAnalyticsFilters(c)
+	if !ok {
+		return
+	}
+	respondWithAnalytics(c, "Outstanding grading computed", filters.values(), func() (interface{}, error) {
+		teachers := []OutstandingGrading{}
+		query := config.DB.WithContext(c.Request.Context()).Table("enrollments").
+			Select(`users.id AS teacher_id, users.name, users.email,
+				COUNT(DISTINCT courses.id) AS courses, COUNT(enrollments.id) AS ungraded`).
+			Joins("JOIN courses ON courses.id = enrollments.course_id AND courses.archived_at IS NULL").
+			Joins("JOIN users ON users.id = courses.teacher_id").
+			Joins("LEFT JOIN grades ON grades.student_id = enrollments.student_id AND grades.course_id = enrollments.course_id").
+			Where("grades.id IS NULL").
+			Group("users.id, users.name, users.email").
+			Order("ungraded DESC, users.name")
+		if err := filters.apply(query).Scan(&teachers).Error; err != nil {
+			return nil, err
+		}
+		return teachers, nil
+	})
+}
+
+// TermTrend summarises one term and its change from the term before
+type TermTrend struct {
+	Term               string   `json:"term"`
+	Courses            int64    `json:"courses"`
+	Enrollments        int64    `json:"enrollments"`
+	Graded             int64    `json:"graded"`
+	Average            *float64 `json:"average"`
+	PassRate           *float64 `json:"pass_rate"`
+	AverageGradePoints *float64 `json:"average_grade_points"`
+	AverageChange      *float64 `json:"average_change"`   // Points since the previous term
+	PassRateChange     *float64 `json:"pass_rate_change"` // Percentage points since the previous term
+}
+
+// TrendAnalytics returns term-over-term figures, oldest term first. Terms are
+// ordered by models.CompareTerms, and ?department= restricts them.
+func TrendAnalytics(c *gin.Context) {
+	filters, ok := parseAnalyticsFilters(c)
+	if !ok {
+		return
+	}
+	// Trends span every term, so only the department filters them
+	filters.Term = ""
+	respondWithAnalytics(c, "Term trends computed", filters.values(), func() (interface{}, error) {
+		var rows []struct {
+			Term        string
+			Courses     int64
+			Enrollments int64
+			Graded      int64
+			Average     *float64
+			Passed      int64
+			GradePoints *float64
+		}
+		query := config.DB.WithContext(c.Request.Context()).Table("enrollments").
+			Select(`courses.term, COUNT(DISTINCT courses.id) AS courses, COUNT(enrollments.id) AS enrollments,
+				COUNT(grades.id) AS graded, AVG(grades.marks) AS average,
+				SUM(CASE WHEN grades.marks >= ? THEN 1 ELSE 0 END) AS passed,
+				AVG(CASE WHEN grades.id IS NULL THEN NULL ELSE `+services.GradePointsSQL+` END) AS grade_points`, services.PassMark).
+			Joins("JOIN courses ON courses.id = enrollments.course_id").
+			Joins("LEFT JOIN grades ON grades.student_id = enrollments.student_id AND grades.course_id = enrollments.course_id").
+			Where("courses.term <> ''").
+			Group("courses.term")
+		query = filters.apply(query)
+		if err := query.Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		sort.Slice(rows, func(i, j int) bool { return models.CompareTerms(rows[i].Term, rows[j].Term) < 0 })
+
+		trends := make([]TermTrend, 0, len(rows))
+		for i, row := range rows {
+			trend := TermTrend{Term: row.Term, Courses: row.Courses, Enrollments: row.Enrollments, Graded: row.Graded}
+			if row.Graded > 0 {
+				trend.Average = roundedPtr(*row.Average)
+				trend.PassRate = roundedPtr(float64(row.Passed) * 100 / float64(row.Graded))
+				trend.AverageGradePoints = roundedPtr(*row.GradePoints)
+			}
+			if i > 0 {
+				previous := trends[i-1]
+				if trend.Average != nil && previous.Average != nil {
+					trend.AverageChange = roundedPtr(*trend.Average - *previous.Average)
+					trend.PassRateChange = roundedPtr(*trend.PassRate - *previous.PassRate)
+				}
+			}
+			trends = append(trends, trend)
+		}
+		return trends, nil
+	})
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"errors"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Course abilities a teacher can delegate to a teaching assistant.
+// An empty ability means only the course teacher is allowed.
+const (
+	abilityOwner       = ""
+	abilityViewRoster  = "view_roster"
+	abilityEnterScores = "enter_scores"
+)
+
+// authorizeCourse loads the course from the :courseId path parameter and checks
+// that the caller teaches it, or assists in it with any of the given abilities.
+// It writes the error response itself and returns false when access is denied.
+func authorizeCourse(c *gin.Context, abilities ...string) (models.Course, bool) {
+	userID := c.MustGet("userID").(uint)
+
+	var course models.Course
+	courseID, err := strconv.Atoi(c.Param("courseId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid course ID")
+		return course, false
+	}
+
+	if err := config.DB.WithContext(c.Request.Context()).First(&course, courseID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Course not found or you don't have access")
+		return course, false
+	}
+
+	if course.TeacherID == userID {
+		return course, true
+	}
+
+	var assistant models.CourseAssistant
+	if err := config.DB.WithContext(c.Request.Context()).Where("course_id = ? AND user_id = ?", course.ID, userID).First(&assistant).Error; err == nil {
+		for _, ability := range abilities {
+			if (ability == abilityViewRoster && assistant.CanViewRoster) ||
+				(ability == abilityEnterScores && assistant.CanEnterScores) {
+				return course, true
+			}
+		}
+	}
+
+	utils.ErrorResponse(c, http.StatusForbidden, "Course not found or you don't have access")
+	return course, false
+}
+
+type AssistantInput struct {
+	UserID         uint `json:"user_id" binding:"required"`
+	CanViewRoster  bool `json:"can_view_roster"`
+	CanEnterScores bool `json:"can_enter_scores"`
+}
+
+// AddOrUpdateAssistant delegates abilities in the teacher's course to a teaching assistant
+func AddOrUpdateAssistant(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+
+	var input AssistantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.UserID == course.TeacherID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "The course teacher cannot be an assistant")
+		return
+	}
+
+	var user models.User
+	if err := config.DB.WithContext(c.Request.Context()).First(&user, input.UserID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "User not found")
+		return
+	}
+
+	// Students and guardians would gain roster and score access they must never have
+	if user.Role == models.RoleStudent || user.Role == models.RoleGuardian {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Only staff can assist in a course")
+		return
+	}
+
+	var assistant models.CourseAssistant
+	err := config.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("course_id = ? AND user_id = ?", course.ID, user.ID).First(&assistant).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		assistant.CourseID = course.ID
+		assistant.UserID = user.ID
+		assistant.CanViewRoster = input.CanViewRoster
+		assistant.CanEnterScores = input.CanEnterScores
+		if err := tx.Save(&assistant).Error; err != nil {
+			return err
+		}
+
+		// The built-in role grants course.assist so the assistant can reach the teacher endpoints
+		var role models.Role
+		if err := tx.Where("name = ?", models.RoleTeachingAssistant).First(&role).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Association("Roles").Append(&role)
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to save assistant")
+		return
+	}
+
+	recordAudit(c, "course.assistant.save", "course", course.ID, "user="+strconv.Itoa(int(user.ID)))
+	utils.SuccessResponse(c, http.StatusOK, "Assistant saved successfully", assistant)
+}
+
+// ListAssistants returns the teaching assistants of the teacher's course
+func ListAssistants(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+
+	var assistants []models.CourseAssistant
+	if err := config.DB.WithContext(c.Request.Context()).Preload("User").Where("course_id = ?", course.ID).Find(&assistants).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch assistants")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Assistants fetched successfully", assistants)
+}
+
+// RemoveAssistant revokes every delegation of a user in the teacher's course
+func RemoveAssistant(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	err = config.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("course_id = ? AND user_id = ?", course.ID, userID).Delete(&models.CourseAssistant{}).Error; err != nil {
+			return err
+		}
+
+		// Drop the assistant role once the user no longer assists anywhere
+		var remaining int64
+		if err := tx.Model(&models.CourseAssistant{}).Where("user_id = ?", userID).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return nil
+		}
+		return tx.Exec("DELETE FROM user_roles WHERE user_id = ? AND role_id IN (?)", userID,
+			tx.Model(&models.Role{}).Select("id").Where("name = ?", models.RoleTeachingAssistant)).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove assistant")
+		return
+	}
+
+	recordAudit(c, "course.assistant.remove", "course", course.ID, "user="+strconv.Itoa(userID))
+	utils.SuccessResponse(c, http.StatusOK, "Assistant removed successfully", nil)
+}
+
+// GetAssistedCourses returns the courses the logged-in user assists in, with the delegated abilities
+func GetAssistedCourses(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var assistants []models.CourseAssistant
+	if err := config.DB.WithContext(c.Request.Context()).Preload("Course").Where("user_id = ?", userID).Find(&assistants).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch courses")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Courses fetched successfully", assistants)
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errNotEnrolled = errors.New("student is not enrolled in the course")
+
+type AttendanceEntry struct {
+	StudentID uint   `json:"student_id" binding:"required"`
+	Status    string `json:"status" binding:"required,oneof=present absent late excused"`
+}
+
+type AttendanceInput struct {
+	Date    string            `json:"date" binding:"required"` // YYYY-MM-DD
+	Records []AttendanceEntry `json:"records" binding:"required,min=1,dive"`
+}
+
+// RecordAttendance saves the attendance of one session in the teacher's course.
+// Recording the same date again overwrites the earlier entries.
+func RecordAttendance(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+	teacherID := c.MustGet("userID").(uint)
+
+	var input AttendanceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", input.Date)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid date, expected YYYY-MM-DD")
+		return
+	}
+
+	var records []models.AttendanceRecord
+	err = config.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		for _, entry := range input.Records {
+			var enrollment models.Enrollment
+			if err := tx.Where("student_id = ? AND course_id = ?", entry.StudentID, course.ID).First(&enrollment).Error; err != nil {
+				return errNotEnrolled
+			}
+
+			var record models.AttendanceRecord
+			tx.Where("course_id = ? AND student_id = ? AND date = ?", course.ID, entry.StudentID, date).First(&record)
+			record.CourseID = course.ID
+			record.StudentID = entry.StudentID
+			record.Date = date
+			record.Status = entry.Status
+			record.RecordedByID = teacherID
+			if err := tx.Save(&record).Error; err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	if errors.Is(err, errNotEnrolled) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "One or more students are not enrolled in this course")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to record attendance")
+		return
+	}
+	studentIDs := make([]uint, 0, len(records))
+	for _, record := range records {
+		studentIDs = append(studentIDs, record.StudentID)
+	}
+	reevaluateRisk(studentIDs...)
+
+	utils.SuccessResponse(c, http.StatusOK, "Attendance recorded successfully", records)
+}
+
+// ListCourseAttendance returns the attendance records of the teacher's course, optionally for one date
+func ListCourseAttendance(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+
+	query := config.DB.WithContext(c.Request.Context()).Where("course_id = ?", course.ID).Order("date desc, student_id")
+	if date := c.Query("date"); date != "" {
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid date, expected YYYY-MM-DD")
+			return
+		}
+		query = query.Where("date = ?", parsed)
+	}
+
+	var records []models.AttendanceRecord
+	if err := query.Find(&records).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch attendance")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Attendance fetched successfully", records)
+}
+
+// AttendanceSummary is a student's attendance in one course
+type AttendanceSummary struct {
+	CourseID   uint    `json:"course_id"`
+	CourseName string  `json:"course_name"`
+	Sessions   int     `json:"sessions"`
+	Present    int     `json:"present"`
+	Late       int     `json:"late"`
+	Absent     int     `json:"absent"`
+	Excused    int     `json:"excused"`
+	Rate       float64 `json:"attendance_rate"` // Percentage of non-excused sessions attended
+}
+
+// fetchAttendanceSummary aggregates a student's attendance per course
+func fetchAttendanceSummary(ctx context.Context, studentID uint) ([]AttendanceSummary, error) {
+	summaries := []AttendanceSummary{}
+	err := config.DB.WithContext(ctx).Table("attendance_records").
+		Select(`attendance_records.course_id, courses.name AS course_name,
+			COUNT(*) AS sessions,
+			SUM(CASE WHEN status = 'present' THEN 1 ELSE 0 END) AS present,
+			SUM(CASE WHEN status = 'late' THEN 1 ELSE 0 END) AS late,
+			SUM(CASE WHEN status = 'absent' THEN 1 ELSE 0 END) AS absent,
+			SUM(CASE WHEN status = 'excused' THEN 1 ELSE 0 END) AS excused`).
+		Joins("JOIN courses ON courses.id = attendance_records.course_id").
+		Where("attendance_records.student_id = ?", studentID).
+		Group("attendance_records.course_id, courses.name").
+		Order("attendance_records.course_id").
+		Scan(&summaries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range summaries {
+		if counted := summaries[i].Sessions - summaries[i].Excused; counted > 0 {
+			summaries[i].Rate = float64(summaries[i].Present+summaries[i].Late) / float64(counted) * 100
+		}
+	}
+	return summaries, nil
+}
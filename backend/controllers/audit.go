@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/services"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentActor reads the authenticated principal set by AuthRequired
+func currentActor(c *gin.Context) services.Actor {
+	if c.GetString("actorType") == models.ActorAPIKey {
+		serviceAccountID := c.GetUint("serviceAccountID")
+		return services.Actor{Type: models.ActorAPIKey, ID: c.GetUint("apiKeyID"), ServiceAccountID: &serviceAccountID}
+	}
+	who := services.Actor{Type: models.ActorUser, ID: c.GetUint("userID")}
+	if impersonatorID := c.GetUint("impersonatorID"); impersonatorID != 0 {
+		who.ImpersonatorID = &impersonatorID
+	}
+	return who
+}
+
+// recordAudit writes an audit entry attributed to whoever made the request.
+// Failures are logged rather than returned so auditing never breaks a request
+// that has already succeeded.
+func recordAudit(c *gin.Context, action, resource string, resourceID uint, details string) {
+	who := currentActor(c)
+	entry := models.AuditLog{
+		ActorType:        who.Type,
+		ActorID:          who.ID,
+		ServiceAccountID: who.ServiceAccountID,
+		ImpersonatorID:   who.ImpersonatorID,
+		Action:           action,
+		Resource:         resource,
+		ResourceID:       resourceID,
+		Details:          details,
+	}
+
+	if err := config.DB.WithContext(c.Request.Context()).Create(&entry).Error; err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to write audit log", "action", action, "resource", resource, "resource_id", resourceID, "error", err)
+	}
+}
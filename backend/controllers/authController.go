@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"grade-management-system/config"
+	"grade-management-system/metrics"
 	"grade-management-system/models"
+	"grade-management-system/tracing"
 	"grade-management-system/utils"
 	"net/http"
 	"strings"
@@ -31,8 +33,9 @@ func RegisterStudent(c *gin.Context) {
 	}
 
 	// Check if user already exists
+	// Deleted users keep their email, so they are included in the check
 	var existingUser models.User
-	if err := config.DB.Where("email = ?", input.Email).First(&existingUser).Error; err == nil {
+	if err := config.DB.WithContext(c.Request.Context()).Unscoped().Where("email = ?", strings.ToLower(input.Email)).First(&existingUser).Error; err == nil {
 		utils.ErrorResponse(c, http.StatusConflict, "Email already in use")
 		return
 	}
@@ -50,7 +53,7 @@ func RegisterStudent(c *gin.Context) {
 		Role:     "student", // Forced to student
 	}
 
-	if err := config.DB.Create(&user).Error; err != nil {
+	if err := config.DB.WithContext(c.Request.Context()).Create(&user).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create user")
 		return
 	}
@@ -60,6 +63,8 @@ func RegisterStudent(c *gin.Context) {
 
 // Login authenticates a user and returns a JWT
 func Login(c *gin.Context) {
+	defer func() { metrics.LoginAttempt(metrics.LoginPassword, c.Writer.Status() == http.StatusOK) }()
+
 	var input LoginInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
@@ -67,16 +72,24 @@ func Login(c *gin.Context) {
 	}
 
 	var user models.User
-	if err := config.DB.Where("email = ?", strings.ToLower(input.Email)).First(&user).Error; err != nil {
+	if err := config.DB.WithContext(c.Request.Context()).Where("email = ?", strings.ToLower(input.Email)).First(&user).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
 
-	if !utils.CheckPasswordHash(input.Password, user.Password) {
+	_, span := tracing.Tracer().Start(c.Request.Context(), "bcrypt.compare")
+	passwordMatches := utils.CheckPasswordHash(input.Password, user.Password)
+	span.End()
+	if !passwordMatches {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
 
+	if user.DeactivatedAt != nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Account is deactivated")
+		return
+	}
+
 	token, err := utils.GenerateToken(user.ID, user.Role)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate token")
@@ -88,3 +101,70 @@ func Login(c *gin.Context) {
 		"user":  user,
 	})
 }
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangePassword replaces the logged-in user's password. Users created with a
+// temporary password must call this before any other endpoint.
+func ChangePassword(c *gin.Context) {
+	userID, ok := c.Get("userID")
+	if !ok {
+		// API keys have no password
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: only users can change a password")
+		return
+	}
+
+	var input ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := config.DB.WithContext(c.Request.Context()).First(&user, userID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if !utils.CheckPasswordHash(input.CurrentPassword, user.Password) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Current password is incorrect")
+		return
+	}
+	if input.NewPassword == input.CurrentPassword {
+		utils.ErrorResponse(c, http.StatusBadRequest, "New password must differ from the current one")
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(input.NewPassword)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to hash password")
+		return
+	}
+
+	if err := config.DB.WithContext(c.Request.Context()).Model(&user).Updates(map[string]interface{}{
+		"password":             hashedPassword,
+		"must_change_password": false,
+	}).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to change password")
+		return
+	}
+
+	recordAudit(c, "user.password.change", "user", user.ID, "")
+	utils.SuccessResponse(c, http.StatusOK, "Password changed successfully", nil)
+}
+
+// JWKS publishes the public verification keys so other services can validate
+// tokens issued by this API without sharing a secret
+func JWKS(c *gin.Context) {
+	set, err := utils.PublicJWKS()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load signing keys")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
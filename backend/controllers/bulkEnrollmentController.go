@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"fmt"
+	"grade-management-system/models"
+	"grade-management-system/services"
+	"grade-management-system/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CohortInput selects every active student matching all of the given criteria
+type CohortInput struct {
+	Department   string `json:"department"`
+	FromCourseID uint   `json:"from_course_id"` // Students enrolled in another course
+}
+
+// BulkEnrollInput lists the students to enroll. Students can be given by ID,
+// by email and as a cohort, in any combination.
+type BulkEnrollInput struct {
+	StudentIDs   []uint       `json:"student_ids"`
+	Emails       []string     `json:"emails"`
+	Cohort       *CohortInput `json:"cohort"`
+	AllOrNothing bool         `json:"all_or_nothing"`
+}
+
+// BulkEnrollResult is the outcome for one requested student
+type BulkEnrollResult struct {
+	Row       int    `json:"row,omitempty"`   // Line number, for uploaded files
+	Input     string `json:"input,omitempty"` // The ID or email as requested
+	StudentID uint   `json:"student_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Status    string `json:"status"`
+}
+
+// TeacherBulkEnroll enrolls many students in the teacher's course
+func (h *Handlers) TeacherBulkEnroll(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+
+	// A cohort may only be copied from another course the teacher teaches
+	h.bulkEnroll(c, course, func(courseID uint) bool {
+		source, err := h.Courses.Find(c.Request.Context(), courseID)
+		return err == nil && source.TeacherID == course.TeacherID
+	})
+}
+
+// AdminBulkEnroll enrolls many students in any course
+func (h *Handlers) AdminBulkEnroll(c *gin.Context) {
+	course, ok := h.findManagedCourse(c)
+	if !ok {
+		return
+	}
+
+	h.bulkEnroll(c, course, func(courseID uint) bool {
+		_, err := h.Courses.Find(c.Request.Context(), courseID)
+		return err == nil
+	})
+}
+
+// bulkEnroll reads the students from a JSON body or from an uploaded CSV/XLSX
+// file with a student_id or email column, and enrolls them with
+// EnrollmentService.EnrollMany. Students that cannot be enrolled are reported
+// per entry; with all_or_nothing any of them cancels the whole enrollment.
+// canReadCourse decides whether a cohort may be taken from another course.
+func (h *Handlers) bulkEnroll(c *gin.Context, course models.Course, canReadCourse func(courseID uint) bool) {
+	var (
+		entries      []BulkEnrollResult
+		cohort       *CohortInput
+		allOrNothing bool
+	)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "A CSV or XLSX file is required in the 'file' field")
+			return
+		}
+		sheet, err := utils.ReadUpload(fileHeader)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file: "+err.Error())
+			return
+		}
+		idCol, emailCol := sheet.Column("student_id"), sheet.Column("email")
+		if idCol < 0 && emailCol < 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "The file needs a student_id or email column")
+			return
+		}
+		for i, record := range sheet.Rows {
+			entry := BulkEnrollResult{Row: sheet.Line[i], Input: sheet.Cell(record, idCol)}
+			if entry.Input == "" {
+				entry.Input = strings.ToLower(sheet.Cell(record, emailCol))
+			}
+			entries = append(entries, entry)
+		}
+		allOrNothing = c.PostForm("all_or_nothing") == "true"
+	} else {
+		var input BulkEnrollInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		for _, id := range input.StudentIDs {
+			entries = append(entries, BulkEnrollResult{Input: strconv.FormatUint(uint64(id), 10)})
+		}
+		for _, email := range input.Emails {
+			entries = append(entries, BulkEnrollResult{Input: strings.ToLower(strings.TrimSpace(email))})
+		}
+		cohort, allOrNothing = input.Cohort, input.AllOrNothing
+	}
+
+	if cohort != nil {
+		if cohort.FromCourseID != 0 && !canReadCourse(cohort.FromCourseID) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Cohort course not found or you don't have access")
+			return
+		}
+		students, err := h.Enrollments.Cohort(c.Request.Context(), cohort.Department, cohort.FromCourseID)
+		if err != nil {
+			respondWithServiceError(c, err, "Failed to fetch the cohort")
+			return
+		}
+		for _, student := range students {
+			entries = append(entries, BulkEnrollResult{Input: strconv.FormatUint(uint64(student), 10)})
+		}
+	}
+
+	if len(entries) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No students to enroll")
+		return
+	}
+	if len(entries) > utils.MaxUploadRows {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("At most %d students can be enrolled at once", utils.MaxUploadRows))
+		return
+	}
+
+	students := make([]string, len(entries))
+	for i, entry := range entries {
+		students[i] = entry.Input
+	}
+	outcomes, err := h.Enrollments.EnrollMany(c.Request.Context(), course, students, allOrNothing)
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to enroll students, no students were enrolled")
+		return
+	}
+
+	failed := 0
+	results := make([]BulkEnrollResult, len(outcomes))
+	for i, outcome := range outcomes {
+		result := entries[outcome.Index]
+		if outcome.Student != nil {
+			result.StudentID, result.Name, result.Email = outcome.Student.ID, outcome.Student.Name, outcome.Student.Email
+		}
+		result.Status = outcome.Status
+		results[i] = result
+		if outcome.Failed() {
+			failed++
+		}
+	}
+
+	if allOrNothing && failed > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": fmt.Sprintf("%d of %d students cannot be enrolled; no students were enrolled", failed, len(results)),
+			"data":  bulkEnrollSummary(results),
+		})
+		return
+	}
+
+	summary := bulkEnrollSummary(results)
+	recordAudit(c, "enrollment.bulk_create", "course", course.ID, fmt.Sprintf("enrolled=%d already_enrolled=%d failed=%d", summary["enrolled"], summary["already_enrolled"], failed))
+	utils.SuccessResponse(c, http.StatusOK, "Bulk enrollment completed", summary)
+}
+
+// bulkEnrollSummary counts the results by status
+func bulkEnrollSummary(results []BulkEnrollResult) gin.H {
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	return gin.H{
+		"total":            len(results),
+		"enrolled":         counts[services.EnrollStatusEnrolled],
+		"already_enrolled": counts[services.EnrollStatusAlreadyEnrolled],
+		"not_a_student":    counts[services.EnrollStatusNotAStudent],
+		"not_found":        counts[services.EnrollStatusNotFound],
+		"deactivated":      counts[services.EnrollStatusDeactivated],
+		"skipped":          counts[services.EnrollStatusSkipped],
+		"results":          results,
+	}
+}
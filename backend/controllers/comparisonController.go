@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/utils"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reasons a grade comes without a class comparison
+const (
+	comparisonHiddenByTeacher = "hidden_by_teacher"
+	comparisonClassTooSmall   = "class_too_small"
+)
+
+// comparisonMinClassSize is the number of graded students a course needs before
+// comparisons are shown, so that no single classmate's marks can be inferred.
+// It is configurable with COMPARISON_MIN_CLASS_SIZE.
+func comparisonMinClassSize() int {
+	if size, err := strconv.Atoi(os.Getenv("COMPARISON_MIN_CLASS_SIZE")); err == nil && size > 0 {
+		return size
+	}
+	return 5
+}
+
+// GradeComparison places a grade within its course without identifying
+// anyone else. Only Hidden and Reason are set when the comparison is withheld.
+type GradeComparison struct {
+	Hidden        bool     `json:"hidden"`
+	Reason        string   `json:"reason,omitempty"`
+	ClassSize     int64    `json:"class_size,omitempty"` // Graded students, including this one
+	CourseAverage *float64 `json:"course_average,omitempty"`
+	Percentile    *float64 `json:"percentile,omitempty"` // Share of classmates scoring lower, counting ties as half
+	RankBand      string   `json:"rank_band,omitempty"`
+}
+
+// GradeWithComparison is a grade with its optional class comparison
+type GradeWithComparison struct {
+	models.Grade
+	Comparison *GradeComparison `json:"comparison,omitempty"`
+}
+
+// respondWithGrades writes the grades response shared by the student and
+// guardian endpoints. With ?compare=true each grade includes a class comparison.
+func (h *Handlers) respondWithGrades(c *gin.Context, studentID uint) {
+	grades, err := h.Grades.StudentGrades(c.Request.Context(), studentID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch grades")
+		return
+	}
+	if c.Query("compare") != "true" {
+		utils.SuccessResponse(c, http.StatusOK, "Grades retrieved", grades)
+		return
+	}
+
+	comparisons, err := compareGrades(c.Request.Context(), studentID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to compare grades")
+		return
+	}
+
+	result := make([]GradeWithComparison, 0, len(grades))
+	for _, grade := range grades {
+		comparison := comparisons[grade.CourseID]
+		if grade.Course.HideComparisons {
+			comparison = GradeComparison{Hidden: true, Reason: comparisonHiddenByTeacher}
+		}
+		result = append(result, GradeWithComparison{Grade: grade, Comparison: &comparison})
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Grades retrieved", result)
+}
+
+// compareGrades compares each of the student's grades with the rest of its
+// course in a single aggregate query, keyed by course ID
+func compareGrades(ctx context.Context, studentID uint) (map[uint]GradeComparison, error) {
+	var rows []struct {
+		CourseID  uint
+		ClassSize int64
+		Average   float64
+		Below     int64
+		Equal     int64
+	}
+	err := config.DB.WithContext(ctx).Table("grades AS mine").
+		Select(`mine.course_id, COUNT(others.id) AS class_size, AVG(others.marks) AS average,
+			SUM(CASE WHEN others.marks < mine.marks THEN 1 ELSE 0 END) AS below,
+			SUM(CASE WHEN others.marks = mine.marks THEN 1 ELSE 0 END) AS equal`).
+		Joins("JOIN grades AS others ON others.course_id = mine.course_id").
+		Where("mine.student_id = ?", studentID).
+		Group("mine.course_id, mine.marks").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	minSize := int64(comparisonMinClassSize())
+	comparisons := make(map[uint]GradeComparison, len(rows))
+	for _, row := range rows {
+		if row.ClassSize < minSize || row.ClassSize < 2 {
+			comparisons[row.CourseID] = GradeComparison{Hidden: true, Reason: comparisonClassTooSmall}
+			continue
+		}
+		// The student's own grade is one of the ties
+		percentile := (float64(row.Below) + float64(row.Equal-1)/2) * 100 / float64(row.ClassSize-1)
+		comparisons[row.CourseID] = GradeComparison{
+			ClassSize:     row.ClassSize,
+			CourseAverage: roundedPtr(row.Average),
+			Percentile:    roundedPtr(percentile),
+			RankBand:      rankBand(percentile),
+		}
+	}
+	return comparisons, nil
+}
+
+// rankBand names the coarse band of a percentile
+func rankBand(percentile float64) string {
+	switch {
+	case percentile >= 90:
+		return "top 10%"
+	case percentile >= 75:
+		return "top 25%"
+	case percentile >= 50:
+		return "top 50%"
+	default:
+		return "bottom 50%"
+	}
+}
+
+type CourseSettingsInput struct {
+	HideComparisons *bool `json:"hide_comparisons"`
+}
+
+// UpdateCourseSettings lets the teacher change their course's settings, such as
+// hiding class comparisons from students and guardians
+func UpdateCourseSettings(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+
+	var input CourseSettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.HideComparisons != nil && *input.HideComparisons != course.HideComparisons {
+		course.HideComparisons = *input.HideComparisons
+		if err := config.DB.WithContext(c.Request.Context()).Model(&course).Update("hide_comparisons", course.HideComparisons).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update course settings")
+			return
+		}
+		recordAudit(c, "course.settings", "course", course.ID, fmt.Sprintf("hide_comparisons=%t", course.HideComparisons))
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Course settings updated", gin.H{"hide_comparisons": course.HideComparisons})
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/services"
+	"grade-management-system/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ComponentInput struct {
+	Name     string  `json:"name" binding:"required"`
+	Weight   float64 `json:"weight" binding:"min=0,max=100"`
+	MaxScore float64 `json:"max_score" binding:"required,gt=0"`
+}
+
+// CreateComponent adds an assessment component to the teacher's course
+func CreateComponent(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+
+	var input ComponentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	component := models.AssessmentComponent{
+		CourseID: course.ID,
+		Name:     input.Name,
+		Weight:   input.Weight,
+		MaxScore: input.MaxScore,
+	}
+
+	if err := config.DB.WithContext(c.Request.Context()).Create(&component).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create component. Ensure the name is unique within the course.")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Component created successfully", component)
+}
+
+// ListComponents returns the assessment components of a course. Assistants who
+// enter scores need them too, so either ability is enough.
+func ListComponents(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityViewRoster, abilityEnterScores)
+	if !ok {
+		return
+	}
+
+	var components []models.AssessmentComponent
+	if err := config.DB.WithContext(c.Request.Context()).Where("course_id = ?", course.ID).Order("id").Find(&components).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch components")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Components fetched successfully", components)
+}
+
+// GetCourseRoster returns the enrolled students of a course
+func GetCourseRoster(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityViewRoster)
+	if !ok {
+		return
+	}
+
+	var students []models.User
+	if err := config.DB.WithContext(c.Request.Context()).Joins("JOIN enrollments ON enrollments.student_id = users.id").
+		Where("enrollments.course_id = ?", course.ID).Order("users.name").Find(&students).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch roster")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Roster fetched successfully", students)
+}
+
+type ComponentScoreInput struct {
+	StudentID uint    `json:"student_id" binding:"required"`
+	Score     float64 `json:"score" binding:"min=0"`
+}
+
+// AddOrUpdateComponentScore records a student's score in a component.
+// Allowed for the course teacher and assistants who may enter scores.
+func (h *Handlers) AddOrUpdateComponentScore(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityEnterScores)
+	if !ok {
+		return
+	}
+	componentID, ok := idParam(c, "componentId", "Component not found in this course")
+	if !ok {
+		return
+	}
+
+	var input ComponentScoreInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	change := services.ScoreChange{CourseID: course.ID, ComponentID: componentID, StudentID: input.StudentID, Score: input.Score}
+	score, err := h.Grades.SaveScore(c.Request.Context(), change, currentActor(c))
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to save score")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Score saved successfully", score)
+}
+
+// ListComponentScores returns all scores entered for a component, to the same
+// callers as ListComponents
+func ListComponentScores(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityViewRoster, abilityEnterScores)
+	if !ok {
+		return
+	}
+
+	var scores []models.ComponentScore
+	if err := config.DB.WithContext(c.Request.Context()).Joins("JOIN assessment_components ON assessment_components.id = component_scores.component_id").
+		Where("component_scores.component_id = ? AND assessment_components.course_id = ?", c.Param("componentId"), course.ID).
+		Order("component_scores.student_id").Find(&scores).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch scores")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Scores fetched successfully", scores)
+}
+
+// GetGradeHistory returns every recorded grade and score change in the teacher's course
+func GetGradeHistory(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+
+	query := config.DB.WithContext(c.Request.Context()).Where("course_id = ?", course.ID).Order("id desc")
+	if studentID := c.Query("student_id"); studentID != "" {
+		query = query.Where("student_id = ?", studentID)
+	}
+
+	var history []models.GradeHistory
+	if err := query.Find(&history).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch grade history")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Grade history fetched successfully", history)
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"fmt"
+	"grade-management-system/models"
+	"grade-management-system/services"
+	"grade-management-system/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateCourseInput holds the course details an admin may change; omitted fields are left as they are
+type UpdateCourseInput struct {
+	Name        *string `json:"name" binding:"omitempty,min=1"`
+	Description *string `json:"description"`
+	Term        *string `json:"term" binding:"omitempty,max=50"`
+	Department  *string `json:"department" binding:"omitempty,max=100"`
+}
+
+// UpdateCourse edits a course's name, description, term or department
+func (h *Handlers) UpdateCourse(c *gin.Context) {
+	course, ok := h.findManagedCourse(c)
+	if !ok {
+		return
+	}
+
+	var input UpdateCourseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	changes, err := h.Courses.Update(c.Request.Context(), &course, services.CourseUpdate{
+		Name: input.Name, Description: input.Description, Term: input.Term, Department: input.Department,
+	})
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to update course")
+		return
+	}
+	if len(changes) == 0 {
+		utils.SuccessResponse(c, http.StatusOK, "Nothing to update", course)
+		return
+	}
+
+	recordAudit(c, "course.update", "course", course.ID, strings.Join(changes, ", "))
+	utils.SuccessResponse(c, http.StatusOK, "Course updated successfully", course)
+}
+
+type ReassignTeacherInput struct {
+	TeacherID uint `json:"teacher_id" binding:"required"`
+}
+
+// ReassignTeacher moves a course to another teacher. If the new teacher was
+// assisting in the course, that delegation is removed.
+func (h *Handlers) ReassignTeacher(c *gin.Context) {
+	course, ok := h.findManagedCourse(c)
+	if !ok {
+		return
+	}
+
+	var input ReassignTeacherInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	previousTeacherID := course.TeacherID
+	changed, err := h.Courses.Reassign(c.Request.Context(), &course, input.TeacherID)
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to reassign course")
+		return
+	}
+	if !changed {
+		utils.SuccessResponse(c, http.StatusOK, "Course is already assigned to this teacher", course)
+		return
+	}
+
+	recordAudit(c, "course.reassign", "course", course.ID, fmt.Sprintf("teacher %d->%d", previousTeacherID, input.TeacherID))
+	utils.SuccessResponse(c, http.StatusOK, "Course reassigned successfully", course)
+}
+
+// ArchiveCourse closes a course to new enrollments while keeping its grades
+func (h *Handlers) ArchiveCourse(c *gin.Context) {
+	course, ok := h.findManagedCourse(c)
+	if !ok {
+		return
+	}
+
+	changed, err := h.Courses.Archive(c.Request.Context(), &course)
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to archive course")
+		return
+	}
+	if changed {
+		recordAudit(c, "course.archive", "course", course.ID, "")
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Course archived", course)
+}
+
+// UnarchiveCourse reopens an archived course
+func (h *Handlers) UnarchiveCourse(c *gin.Context) {
+	course, ok := h.findManagedCourse(c)
+	if !ok {
+		return
+	}
+
+	changed, err := h.Courses.Unarchive(c.Request.Context(), &course)
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to unarchive course")
+		return
+	}
+	if changed {
+		recordAudit(c, "course.unarchive", "course", course.ID, "")
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Course unarchived", course)
+}
+
+// DeleteCourse permanently removes a course that has no grades or scores.
+// Courses with grades should be archived instead.
+func (h *Handlers) DeleteCourse(c *gin.Context) {
+	course, ok := h.findManagedCourse(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Courses.Delete(c.Request.Context(), &course); err != nil {
+		respondWithServiceError(c, err, "Failed to delete course")
+		return
+	}
+
+	recordAudit(c, "course.delete", "course", course.ID, course.Name)
+	utils.SuccessResponse(c, http.StatusOK, "Course deleted successfully", nil)
+}
+
+// findManagedCourse loads the course from the :id path parameter, writing a 404 if it doesn't exist
+func (h *Handlers) findManagedCourse(c *gin.Context) (models.Course, bool) {
+	id, ok := idParam(c, "id", "Course not found")
+	if !ok {
+		return models.Course{}, false
+	}
+
+	course, err := h.Courses.Find(c.Request.Context(), id)
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to fetch course")
+		return course, false
+	}
+	return course, true
+}
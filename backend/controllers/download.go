@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"fmt"
+	"grade-management-system/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// startDownload validates the ?format= query (csv by default), sets the
+// download headers and returns a writer for the file. It writes the error
+// response itself and returns false when the format is not supported.
+func startDownload(c *gin.Context, filename string) (utils.SheetWriter, bool) {
+	format := c.DefaultQuery("format", utils.FormatCSV)
+	writer, err := utils.NewSheetWriter(c.Writer, format)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Unsupported format, use csv or xlsx")
+		return nil, false
+	}
+
+	c.Header("Content-Type", utils.SheetContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	c.Status(http.StatusOK)
+	return writer, true
+}
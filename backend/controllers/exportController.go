@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/repository"
+	"grade-management-system/utils"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Exports stream their rows from a database cursor into the response, so even
+// institution-wide exports are never held in memory as a whole.
+
+// ExportCourseGradebook downloads the gradebook of the teacher's course: every
+// enrolled student with their component scores, final marks and letter
+func ExportCourseGradebook(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+
+	var components []models.AssessmentComponent
+	if err := config.DB.WithContext(c.Request.Context()).Where("course_id = ?", course.ID).Order("id").Find(&components).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch components")
+		return
+	}
+
+	// Scores are looked up per student while the roster streams
+	type scoreKey struct{ studentID, componentID uint }
+	scores := map[scoreKey]float64{}
+	var componentScores []models.ComponentScore
+	if err := config.DB.WithContext(c.Request.Context()).Joins("JOIN assessment_components ON assessment_components.id = component_scores.component_id").
+		Where("assessment_components.course_id = ?", course.ID).Find(&componentScores).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch scores")
+		return
+	}
+	for _, score := range componentScores {
+		scores[scoreKey{score.StudentID, score.ComponentID}] = score.Score
+	}
+
+	rows, err := config.DB.WithContext(c.Request.Context()).Table("enrollments").
+		Select("users.id AS student_id, users.roll_number, users.name, users.email, grades.marks, COALESCE(grades.grade_letter, '') AS grade_letter").
+		Joins("JOIN users ON users.id = enrollments.student_id").
+		Joins("LEFT JOIN grades ON grades.student_id = enrollments.student_id AND grades.course_id = enrollments.course_id").
+		Where("enrollments.course_id = ?", course.ID).
+		Order("users.name, users.id").
+		Rows()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to export gradebook")
+		return
+	}
+	defer rows.Close()
+
+	writer, ok := startDownload(c, fmt.Sprintf("course-%d-gradebook", course.ID))
+	if !ok {
+		return
+	}
+
+	header := []interface{}{"student_id", "roll_number", "name", "email"}
+	for _, component := range components {
+		header = append(header, fmt.Sprintf("%s (/%s)", component.Name, strconv.FormatFloat(component.MaxScore, 'f', -1, 64)))
+	}
+	writer.WriteRow(append(header, "marks", "grade_letter")...)
+
+	streamRows(c, rows, writer, func(scan func(dest interface{}) error) ([]interface{}, error) {
+		var student repository.RosterEntry
+		if err := scan(&student); err != nil {
+			return nil, err
+		}
+
+		cells := []interface{}{student.StudentID, optional(student.RollNumber), student.Name, student.Email}
+		for _, component := range components {
+			if score, ok := scores[scoreKey{student.StudentID, component.ID}]; ok {
+				cells = append(cells, score)
+			} else {
+				cells = append(cells, nil)
+			}
+		}
+		return append(cells, optional(student.Marks), student.GradeLetter), nil
+	})
+}
+
+// ExportMyRecord downloads the logged-in student's academic record
+func ExportMyRecord(c *gin.Context) {
+	exportStudentRecord(c, c.MustGet("userID").(uint))
+}
+
+// ExportStudentRecord downloads the academic record of any student
+func ExportStudentRecord(c *gin.Context) {
+	studentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	var student models.User
+	if err := config.DB.WithContext(c.Request.Context()).Unscoped().First(&student, studentID).Error; err != nil || student.Role != models.RoleStudent {
+		utils.ErrorResponse(c, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	exportStudentRecord(c, student.ID)
+}
+
+// exportStudentRecord writes one row per course the student is enrolled in
+func exportStudentRecord(c *gin.Context, studentID uint) {
+	query := config.DB.WithContext(c.Request.Context()).Table("enrollments").
+		Select("courses.id AS course_id, courses.name AS course_name, courses.term, courses.department, grades.marks, COALESCE(grades.grade_letter, '') AS grade_letter").
+		Joins("JOIN courses ON courses.id = enrollments.course_id").
+		Joins("LEFT JOIN grades ON grades.student_id = enrollments.student_id AND grades.course_id = enrollments.course_id").
+		Where("enrollments.student_id = ?", studentID)
+	query, err := orderByTerm(query, "courses.name")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to export student record")
+		return
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to export student record")
+		return
+	}
+	defer rows.Close()
+
+	writer, ok := startDownload(c, fmt.Sprintf("student-%d-record", studentID))
+	if !ok {
+		return
+	}
+
+	writer.WriteRow("course_id", "course", "term", "department", "marks", "grade_letter")
+	streamRows(c, rows, writer, func(scan func(dest interface{}) error) ([]interface{}, error) {
+		var record struct {
+			CourseID    uint
+			CourseName  string
+			Term        string
+			Department  string
+			Marks       *float64
+			GradeLetter string
+		}
+		if err := scan(&record); err != nil {
+			return nil, err
+		}
+		return []interface{}{record.CourseID, record.CourseName, record.Term, record.Department, optional(record.Marks), record.GradeLetter}, nil
+	})
+}
+
+// ExportInstitutionGrades downloads every enrollment with its grade across all
+// courses, optionally filtered by ?term= and ?department=
+func ExportInstitutionGrades(c *gin.Context) {
+	query := config.DB.WithContext(c.Request.Context()).Table("enrollments").
+		Select(`courses.id AS course_id, courses.name AS course_name, courses.term, courses.department,
+			teachers.name AS teacher_name, students.id AS student_id, students.roll_number,
+			students.name AS student_name, students.email AS student_email,
+			grades.marks, COALESCE(grades.grade_letter, '') AS grade_letter`).
+		Joins("JOIN courses ON courses.id = enrollments.course_id").
+		Joins("JOIN users AS teachers ON teachers.id = courses.teacher_id").
+		Joins("JOIN users AS students ON students.id = enrollments.student_id").
+		Joins("LEFT JOIN grades ON grades.student_id = enrollments.student_id AND grades.course_id = enrollments.course_id")
+	if term := c.Query("term"); term != "" {
+		query = query.Where("courses.term = ?", term)
+	}
+	if department := c.Query("department"); department != "" {
+		query = query.Where("courses.department = ?", department)
+	}
+	query, err := orderByTerm(query, "courses.department, courses.name, students.name")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to export grades")
+		return
+	}
+
+	recordAudit(c, "grade.export", "course", 0, fmt.Sprintf("term=%q department=%q", c.Query("term"), c.Query("department")))
+	rows, err := query.Rows()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to export grades")
+		return
+	}
+	defer rows.Close()
+
+	writer, ok := startDownload(c, "grades")
+	if !ok {
+		return
+	}
+
+	writer.WriteRow("course_id", "course", "term", "department", "teacher", "student_id", "roll_number", "student", "email", "marks", "grade_letter")
+	streamRows(c, rows, writer, func(scan func(dest interface{}) error) ([]interface{}, error) {
+		var record struct {
+			CourseID     uint
+			CourseName   string
+			Term         string
+			Department   string
+			TeacherName  string
+			StudentID    uint
+			RollNumber   *string
+			StudentName  string
+			StudentEmail string
+			Marks        *float64
+			GradeLetter  string
+		}
+		if err := scan(&record); err != nil {
+			return nil, err
+		}
+		return []interface{}{
+			record.CourseID, record.CourseName, record.Term, record.Department, record.TeacherName,
+			record.StudentID, optional(record.RollNumber), record.StudentName, record.StudentEmail,
+			optional(record.Marks), record.GradeLetter,
+		}, nil
+	})
+}
+
+// orderByTerm orders query chronologically by courses.term, as
+// models.CompareTerms does, and then by the columns in then. SQL can't parse
+// term names, so the distinct terms are ranked here and the query orders by
+// each course's rank. The whole ORDER BY is one expression because GORM drops
+// an expression when another Order is merged into it.
+func orderByTerm(query *gorm.DB, then string) (*gorm.DB, error) {
+	var terms []string
+	if err := config.DB.WithContext(query.Statement.Context).Model(&models.Course{}).
+		Where("term IS NOT NULL").Distinct("term").Pluck("term", &terms).Error; err != nil {
+		return nil, err
+	}
+	if len(terms) == 0 {
+		return query.Order(then), nil
+	}
+	sort.Slice(terms, func(i, j int) bool { return models.CompareTerms(terms[i], terms[j]) < 0 })
+
+	var order strings.Builder
+	vars := make([]interface{}, len(terms))
+	order.WriteString("CASE courses.term")
+	for i, term := range terms {
+		fmt.Fprintf(&order, " WHEN ? THEN %d", i)
+		vars[i] = term
+	}
+	order.WriteString(" ELSE -1 END, " + then)
+	return query.Order(clause.OrderBy{Expression: clause.Expr{SQL: order.String(), Vars: vars}}), nil
+}
+
+// streamRows converts each database row into cells and writes it, then
+// finishes the file. The response has already started, so failures can only be logged.
+func streamRows(c *gin.Context, rows *sql.Rows, writer utils.SheetWriter, convert func(scan func(dest interface{}) error) ([]interface{}, error)) {
+	scan := func(dest interface{}) error {
+		return config.DB.WithContext(c.Request.Context()).ScanRows(rows, dest)
+	}
+
+	for rows.Next() {
+		cells, err := convert(scan)
+		if err == nil {
+			err = writer.WriteRow(cells...)
+		}
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Export aborted", "error", err)
+			return
+		}
+	}
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Export aborted", "error", err)
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to finish export", "error", err)
+	}
+}
+
+// optional returns the value behind a pointer, or nil so the cell is left empty
+func optional[T any](value *T) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
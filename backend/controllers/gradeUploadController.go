@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"grade-management-system/repository"
+	"grade-management-system/services"
+	"grade-management-system/utils"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Outcomes of a row in a grade upload
+const (
+	gradeChangeNew       = "new"
+	gradeChangeUpdate    = "update"
+	gradeChangeUnchanged = "unchanged"
+	gradeChangeSkipped   = "skipped" // Marks left blank
+	gradeChangeError     = "error"
+)
+
+// GradeUploadRow compares one uploaded row with the student's current grade
+type GradeUploadRow struct {
+	Row       int      `json:"row"` // Line number in the file
+	StudentID uint     `json:"student_id,omitempty"`
+	Email     string   `json:"email,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	OldMarks  *float64 `json:"old_marks"`
+	NewMarks  *float64 `json:"new_marks"`
+	OldLetter string   `json:"old_letter,omitempty"`
+	NewLetter string   `json:"new_letter,omitempty"`
+	Change    string   `json:"change"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// UploadGrades sets the final marks of many students in the teacher's course from
+// a CSV or XLSX file with a marks column and a student_id or email column.
+// Every row is checked against the roster and the 0-100 range and compared with
+// the current grade. With ?dry_run=true the comparison is returned as a preview;
+// otherwise all changes are applied in one transaction, or none if any row fails.
+func (h *Handlers) UploadGrades(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "A CSV or XLSX file is required in the 'file' field")
+		return
+	}
+	sheet, err := utils.ReadUpload(fileHeader)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file: "+err.Error())
+		return
+	}
+
+	if sheet.Column("marks") < 0 || (sheet.Column("student_id") < 0 && sheet.Column("email") < 0) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "The file needs a marks column and a student_id or email column")
+		return
+	}
+	if len(sheet.Rows) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "The file contains no grades")
+		return
+	}
+
+	rows, err := h.compareGradeUpload(c.Request.Context(), sheet, course.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load the course roster")
+		return
+	}
+
+	counts := map[string]int{}
+	for _, row := range rows {
+		counts[row.Change]++
+	}
+	summary := gin.H{
+		"dry_run":   dryRun,
+		"total":     len(rows),
+		"new":       counts[gradeChangeNew],
+		"updated":   counts[gradeChangeUpdate],
+		"unchanged": counts[gradeChangeUnchanged],
+		"skipped":   counts[gradeChangeSkipped],
+		"invalid":   counts[gradeChangeError],
+		"rows":      rows,
+	}
+
+	if counts[gradeChangeError] > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": fmt.Sprintf("%d of %d rows have errors; no grades were changed", counts[gradeChangeError], len(rows)),
+			"data":  summary,
+		})
+		return
+	}
+	if dryRun {
+		utils.SuccessResponse(c, http.StatusOK, "Preview of the grade changes", summary)
+		return
+	}
+
+	var changes []services.GradeChange
+	for _, row := range rows {
+		if row.Change == gradeChangeNew || row.Change == gradeChangeUpdate {
+			changes = append(changes, services.GradeChange{StudentID: row.StudentID, CourseID: course.ID, Marks: *row.NewMarks})
+		}
+	}
+	if err := h.Grades.SaveGrades(c.Request.Context(), changes, currentActor(c)); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Grade upload failed", "course_id", course.ID, "error", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to save grades, no grades were changed")
+		return
+	}
+
+	recordAudit(c, "grade.upload", "course", course.ID, fmt.Sprintf("new=%d updated=%d", counts[gradeChangeNew], counts[gradeChangeUpdate]))
+	utils.SuccessResponse(c, http.StatusOK, "Grades uploaded successfully", summary)
+}
+
+// compareGradeUpload validates each uploaded row against the course roster and
+// the existing grades
+func (h *Handlers) compareGradeUpload(ctx context.Context, sheet *utils.Sheet, courseID uint) ([]GradeUploadRow, error) {
+	roster, err := h.Grades.CourseRoster(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[uint]*repository.RosterEntry{}
+	byEmail := map[string]*repository.RosterEntry{}
+	for i := range roster {
+		byID[roster[i].StudentID] = &roster[i]
+		byEmail[strings.ToLower(roster[i].Email)] = &roster[i]
+	}
+
+	idCol, emailCol, marksCol := sheet.Column("student_id"), sheet.Column("email"), sheet.Column("marks")
+	seen := map[uint]int{}
+	rows := make([]GradeUploadRow, 0, len(sheet.Rows))
+
+	for i, record := range sheet.Rows {
+		row := GradeUploadRow{Row: sheet.Line[i], Email: strings.ToLower(sheet.Cell(record, emailCol))}
+
+		var student *repository.RosterEntry
+		if idCell := sheet.Cell(record, idCol); idCell != "" {
+			id, err := strconv.ParseUint(idCell, 10, 64)
+			if err != nil {
+				row.Errors = append(row.Errors, "student_id is not a number")
+			} else if student = byID[uint(id)]; student == nil {
+				row.Errors = append(row.Errors, "student is not enrolled in this course")
+			} else if row.Email != "" && !strings.EqualFold(row.Email, student.Email) {
+				row.Errors = append(row.Errors, "student_id and email belong to different students")
+			}
+		} else if row.Email != "" {
+			if student = byEmail[row.Email]; student == nil {
+				row.Errors = append(row.Errors, "student is not enrolled in this course")
+			}
+		} else {
+			row.Errors = append(row.Errors, "student_id or email is required")
+		}
+
+		if student != nil {
+			row.StudentID, row.Email, row.Name = student.StudentID, student.Email, student.Name
+			row.OldMarks, row.OldLetter = student.Marks, student.GradeLetter
+
+			if first, ok := seen[student.StudentID]; ok {
+				row.Errors = append(row.Errors, fmt.Sprintf("student already appears in row %d", first))
+			} else {
+				seen[student.StudentID] = row.Row
+			}
+		}
+
+		marksCell := sheet.Cell(record, marksCol)
+		if marksCell != "" {
+			marks, err := strconv.ParseFloat(marksCell, 64)
+			if err != nil || marks < 0 || marks > 100 {
+				row.Errors = append(row.Errors, "marks must be a number between 0 and 100")
+			} else {
+				row.NewMarks = &marks
+				row.NewLetter = services.GradeLetter(marks)
+			}
+		}
+
+		switch {
+		case len(row.Errors) > 0:
+			row.Change = gradeChangeError
+		case row.NewMarks == nil:
+			row.Change = gradeChangeSkipped
+		case row.OldMarks == nil:
+			row.Change = gradeChangeNew
+		case *row.OldMarks == *row.NewMarks:
+			row.Change = gradeChangeUnchanged
+		default:
+			row.Change = gradeChangeUpdate
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// DownloadGradeTemplate returns a CSV or XLSX file listing the course roster with
+// the current marks, ready to be filled in and uploaded with UploadGrades
+func (h *Handlers) DownloadGradeTemplate(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+
+	roster, err := h.Grades.CourseRoster(c.Request.Context(), course.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load the course roster")
+		return
+	}
+
+	writer, ok := startDownload(c, fmt.Sprintf("course-%d-grades", course.ID))
+	if !ok {
+		return
+	}
+
+	writer.WriteRow("student_id", "email", "name", "marks")
+	for _, student := range roster {
+		writer.WriteRow(student.StudentID, student.Email, student.Name, optional(student.Marks))
+	}
+	if err := writer.Close(); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to write grade template", "course_id", course.ID, "error", err)
+	}
+}
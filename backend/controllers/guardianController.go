@@ -0,0 +1,393 @@
+package controllers
+
+import (
+	"errors"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/utils"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// guardianRevokeMinAge is the age from which students may revoke guardian access
+// themselves, configurable with GUARDIAN_REVOKE_MIN_AGE
+func guardianRevokeMinAge() int {
+	if age, err := strconv.Atoi(os.Getenv("GUARDIAN_REVOKE_MIN_AGE")); err == nil && age > 0 {
+		return age
+	}
+	return 18
+}
+
+// canRevokeGuardians reports whether a student may revoke active guardian links
+func canRevokeGuardians(student models.User) bool {
+	if student.GuardianRevocable {
+		return true
+	}
+	if student.DateOfBirth == nil {
+		return false
+	}
+	return !time.Now().Before(student.DateOfBirth.AddDate(guardianRevokeMinAge(), 0, 0))
+}
+
+type GuardianLinkInput struct {
+	GuardianID uint `json:"guardian_id" binding:"required"`
+	StudentID  uint `json:"student_id" binding:"required"`
+}
+
+// CreateGuardianLink links a guardian to a student. Admin links are active immediately.
+func CreateGuardianLink(c *gin.Context) {
+	adminID := c.MustGet("userID").(uint)
+
+	var input GuardianLinkInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var guardian, student models.User
+	if err := config.DB.WithContext(c.Request.Context()).First(&guardian, input.GuardianID).Error; err != nil || guardian.Role != models.RoleGuardian {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Guardian not found")
+		return
+	}
+	if err := config.DB.WithContext(c.Request.Context()).First(&student, input.StudentID).Error; err != nil || student.Role != models.RoleStudent {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Student not found")
+		return
+	}
+
+	now := time.Now()
+	var link models.GuardianLink
+	err := config.DB.WithContext(c.Request.Context()).Where("guardian_id = ? AND student_id = ?", guardian.ID, student.ID).First(&link).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to link guardian")
+		return
+	}
+	link.GuardianID = guardian.ID
+	link.StudentID = student.ID
+	link.Status = models.GuardianLinkActive
+	link.CreatedByID = adminID
+	link.ApprovedAt = &now
+	link.RevokedAt = nil
+
+	if err := config.DB.WithContext(c.Request.Context()).Save(&link).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to link guardian")
+		return
+	}
+
+	recordAudit(c, "guardian_link.create", "guardian_link", link.ID, "guardian="+strconv.Itoa(int(guardian.ID))+" student="+strconv.Itoa(int(student.ID)))
+	utils.SuccessResponse(c, http.StatusCreated, "Guardian linked successfully", link)
+}
+
+// ListGuardianLinks returns guardian links, optionally filtered by student or guardian
+func ListGuardianLinks(c *gin.Context) {
+	query := config.DB.WithContext(c.Request.Context()).Preload("Guardian").Preload("Student").Order("id")
+	if studentID := c.Query("student_id"); studentID != "" {
+		query = query.Where("student_id = ?", studentID)
+	}
+	if guardianID := c.Query("guardian_id"); guardianID != "" {
+		query = query.Where("guardian_id = ?", guardianID)
+	}
+
+	var links []models.GuardianLink
+	if err := query.Find(&links).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch guardian links")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Guardian links fetched successfully", links)
+}
+
+// RevokeGuardianLink removes a guardian's access to a student
+func RevokeGuardianLink(c *gin.Context) {
+	linkID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid guardian link ID")
+		return
+	}
+
+	var link models.GuardianLink
+	if err := config.DB.WithContext(c.Request.Context()).First(&link, linkID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Guardian link not found")
+		return
+	}
+
+	if !revokeLink(c, &link) {
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Guardian link revoked", link)
+}
+
+type GuardianSettingsInput struct {
+	DateOfBirth       *string `json:"date_of_birth"` // YYYY-MM-DD, null clears it
+	GuardianRevocable bool    `json:"guardian_revocable"`
+}
+
+// UpdateGuardianSettings sets a student's date of birth and whether they may
+// revoke guardian access regardless of age
+func UpdateGuardianSettings(c *gin.Context) {
+	studentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	var student models.User
+	if err := config.DB.WithContext(c.Request.Context()).First(&student, studentID).Error; err != nil || student.Role != models.RoleStudent {
+		utils.ErrorResponse(c, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	var input GuardianSettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	student.DateOfBirth = nil
+	if input.DateOfBirth != nil {
+		dob, err := time.Parse("2006-01-02", *input.DateOfBirth)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid date_of_birth, expected YYYY-MM-DD")
+			return
+		}
+		student.DateOfBirth = &dob
+	}
+	student.GuardianRevocable = input.GuardianRevocable
+
+	if err := config.DB.WithContext(c.Request.Context()).Model(&student).Select("DateOfBirth", "GuardianRevocable").Updates(&student).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update guardian settings")
+		return
+	}
+
+	recordAudit(c, "user.guardian_settings.update", "user", student.ID, "")
+	utils.SuccessResponse(c, http.StatusOK, "Guardian settings updated", student)
+}
+
+type GuardianInviteInput struct {
+	StudentEmail string `json:"student_email" binding:"required,email"`
+}
+
+// InviteStudent asks a student to share their records with the logged-in guardian.
+// The link stays pending until the student approves it.
+func InviteStudent(c *gin.Context) {
+	guardianID := c.MustGet("userID").(uint)
+
+	var input GuardianInviteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var student models.User
+	if err := config.DB.WithContext(c.Request.Context()).Where("email = ? AND role = ?", strings.ToLower(input.StudentEmail), models.RoleStudent).First(&student).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	var link models.GuardianLink
+	if err := config.DB.WithContext(c.Request.Context()).Where("guardian_id = ? AND student_id = ?", guardianID, student.ID).First(&link).Error; err == nil {
+		if link.Status == models.GuardianLinkActive {
+			utils.ErrorResponse(c, http.StatusConflict, "You are already linked to this student")
+			return
+		}
+		if link.Status == models.GuardianLinkRevoked && link.CreatedByID != guardianID {
+			// Access revoked by the student or an admin can only be restored by them
+			utils.ErrorResponse(c, http.StatusForbidden, "Access to this student was revoked")
+			return
+		}
+	}
+
+	link.GuardianID = guardianID
+	link.StudentID = student.ID
+	link.Status = models.GuardianLinkPending
+	link.CreatedByID = guardianID
+	link.ApprovedAt = nil
+	link.RevokedAt = nil
+
+	if err := config.DB.WithContext(c.Request.Context()).Save(&link).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create invitation")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Invitation sent. The student must approve it.", link)
+}
+
+// GetLinkedStudents returns the guardian's links to students, including pending invitations
+func GetLinkedStudents(c *gin.Context) {
+	guardianID := c.MustGet("userID").(uint)
+
+	var links []models.GuardianLink
+	if err := config.DB.WithContext(c.Request.Context()).Preload("Student").Where("guardian_id = ?", guardianID).Order("id").Find(&links).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch linked students")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Linked students fetched successfully", links)
+}
+
+// linkedStudentID resolves :studentId and checks the guardian has an active link to them
+func linkedStudentID(c *gin.Context) (uint, bool) {
+	guardianID := c.MustGet("userID").(uint)
+
+	studentID, err := strconv.Atoi(c.Param("studentId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid student ID")
+		return 0, false
+	}
+
+	var link models.GuardianLink
+	if err := config.DB.WithContext(c.Request.Context()).Where("guardian_id = ? AND student_id = ? AND status = ?", guardianID, studentID, models.GuardianLinkActive).First(&link).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "You are not linked to this student")
+		return 0, false
+	}
+	return uint(studentID), true
+}
+
+// GetLinkedStudentCourses returns the linked student's enrolled courses
+func (h *Handlers) GetLinkedStudentCourses(c *gin.Context) {
+	studentID, ok := linkedStudentID(c)
+	if !ok {
+		return
+	}
+
+	courses, err := h.Enrollments.StudentCourses(c.Request.Context(), studentID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch enrolled courses")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Enrolled courses retrieved", courses)
+}
+
+// GetLinkedStudentGrades returns the linked student's grades
+func (h *Handlers) GetLinkedStudentGrades(c *gin.Context) {
+	studentID, ok := linkedStudentID(c)
+	if !ok {
+		return
+	}
+
+	h.respondWithGrades(c, studentID)
+}
+
+// GetLinkedStudentGPA returns the linked student's GPA
+func (h *Handlers) GetLinkedStudentGPA(c *gin.Context) {
+	studentID, ok := linkedStudentID(c)
+	if !ok {
+		return
+	}
+
+	h.respondWithGPA(c, studentID)
+}
+
+// GetLinkedStudentAttendance returns the linked student's attendance summary
+func GetLinkedStudentAttendance(c *gin.Context) {
+	studentID, ok := linkedStudentID(c)
+	if !ok {
+		return
+	}
+
+	summary, err := fetchAttendanceSummary(c.Request.Context(), studentID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch attendance")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Attendance retrieved", summary)
+}
+
+// GetMyGuardians returns the student's guardian links, including pending invitations
+func GetMyGuardians(c *gin.Context) {
+	studentID := c.MustGet("userID").(uint)
+
+	var links []models.GuardianLink
+	if err := config.DB.WithContext(c.Request.Context()).Preload("Guardian").Where("student_id = ?", studentID).Order("id").Find(&links).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch guardians")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Guardians fetched successfully", links)
+}
+
+// findMyGuardianLink loads :linkId and checks it belongs to the logged-in student
+func findMyGuardianLink(c *gin.Context) (models.GuardianLink, bool) {
+	studentID := c.MustGet("userID").(uint)
+	linkID, err := strconv.ParseUint(c.Param("linkId"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid guardian link ID")
+		return models.GuardianLink{}, false
+	}
+
+	var link models.GuardianLink
+	if err := config.DB.WithContext(c.Request.Context()).Where("id = ? AND student_id = ?", linkID, studentID).First(&link).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Guardian link not found")
+		return link, false
+	}
+	return link, true
+}
+
+// ApproveGuardian accepts a pending guardian invitation
+func ApproveGuardian(c *gin.Context) {
+	link, ok := findMyGuardianLink(c)
+	if !ok {
+		return
+	}
+
+	if link.Status != models.GuardianLinkPending {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Only pending invitations can be approved")
+		return
+	}
+
+	now := time.Now()
+	link.Status = models.GuardianLinkActive
+	link.ApprovedAt = &now
+	if err := config.DB.WithContext(c.Request.Context()).Save(&link).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to approve guardian")
+		return
+	}
+
+	recordAudit(c, "guardian_link.approve", "guardian_link", link.ID, "")
+	utils.SuccessResponse(c, http.StatusOK, "Guardian approved", link)
+}
+
+// RevokeGuardian declines a pending invitation, or revokes an active link when
+// the student is old enough or has been allowed to by an admin
+func RevokeGuardian(c *gin.Context) {
+	link, ok := findMyGuardianLink(c)
+	if !ok {
+		return
+	}
+
+	if link.Status == models.GuardianLinkActive {
+		var student models.User
+		if err := config.DB.WithContext(c.Request.Context()).First(&student, link.StudentID).Error; err != nil || !canRevokeGuardians(student) {
+			utils.ErrorResponse(c, http.StatusForbidden, "You are not allowed to revoke guardian access. Contact an administrator.")
+			return
+		}
+	}
+
+	if !revokeLink(c, &link) {
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Guardian access revoked", link)
+}
+
+// revokeLink marks a link as revoked by the current user and audits it
+func revokeLink(c *gin.Context, link *models.GuardianLink) bool {
+	now := time.Now()
+	link.Status = models.GuardianLinkRevoked
+	link.RevokedAt = &now
+	// The revoker owns the link from now on, so a guardian cannot simply re-invite
+	link.CreatedByID = c.MustGet("userID").(uint)
+	if err := config.DB.WithContext(c.Request.Context()).Save(link).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke guardian access")
+		return false
+	}
+
+	recordAudit(c, "guardian_link.revoke", "guardian_link", link.ID, "")
+	return true
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"errors"
+	"grade-management-system/services"
+	"grade-management-system/utils"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handlers are the endpoints backed by the domain services. They parse the
+// request, call a service and write the response; the business rules live in
+// the services, which main.go builds and injects.
+type Handlers struct {
+	Grades      services.GradingService
+	Enrollments services.EnrollmentService
+	Users       services.UserService
+	Courses     services.CourseService
+}
+
+// NewHandlers returns the handlers for the given services
+func NewHandlers(grades services.GradingService, enrollments services.EnrollmentService, users services.UserService, courses services.CourseService) *Handlers {
+	return &Handlers{Grades: grades, Enrollments: enrollments, Users: users, Courses: courses}
+}
+
+// serviceErrorStatus maps the kinds of service errors to HTTP status codes
+var serviceErrorStatus = map[services.Kind]int{
+	services.KindInvalid:   http.StatusBadRequest,
+	services.KindNotFound:  http.StatusNotFound,
+	services.KindForbidden: http.StatusForbidden,
+	services.KindConflict:  http.StatusConflict,
+}
+
+// respondWithServiceError writes the error returned by a service. Refusals are
+// shown as they are; anything else is logged and reported with fallback.
+func respondWithServiceError(c *gin.Context, err error, fallback string) {
+	var refusal *services.Error
+	if errors.As(err, &refusal) {
+		utils.ErrorResponse(c, serviceErrorStatus[refusal.Kind], refusal.Message)
+		return
+	}
+	slog.ErrorContext(c.Request.Context(), "Request failed", "route", c.FullPath(), "error", err)
+	utils.ErrorResponse(c, http.StatusInternalServerError, fallback)
+}
+
+// idParam parses a numeric path parameter, writing a 404 with notFound if it isn't one
+func idParam(c *gin.Context, name, notFound string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, notFound)
+		return 0, false
+	}
+	return uint(id), true
+}
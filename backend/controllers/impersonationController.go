@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"fmt"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImpersonateInput struct {
+	UserID      uint   `json:"user_id" binding:"required"`
+	Reason      string `json:"reason" binding:"required"`
+	AllowWrites bool   `json:"allow_writes"`
+}
+
+// Impersonate issues a short-lived token that lets an admin see the system as
+// another user. The session is read-only unless writes are explicitly allowed,
+// and every request made with it is written to the audit log.
+func Impersonate(c *gin.Context) {
+	adminID := c.MustGet("userID").(uint)
+
+	if c.GetUint("impersonatorID") != 0 {
+		utils.ErrorResponse(c, http.StatusForbidden, "Cannot start an impersonation from an impersonated session")
+		return
+	}
+
+	var input ImpersonateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.UserID == adminID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "You cannot impersonate yourself")
+		return
+	}
+
+	var target models.User
+	if err := config.DB.WithContext(c.Request.Context()).First(&target, input.UserID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	// Impersonating an admin would let one admin act with a colleague's
+	// privileges. Admin permissions can also come from additional or custom
+	// roles, so the target's effective permissions are checked, not its role.
+	permissions, err := models.UserPermissions(config.DB.WithContext(c.Request.Context()), target.ID, target.Role)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to resolve permissions")
+		return
+	}
+	for _, permission := range permissions {
+		if models.IsAdminPermission(permission) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Users with admin permissions cannot be impersonated")
+			return
+		}
+	}
+
+	token, expiresAt, err := utils.GenerateImpersonationToken(target.ID, target.Role, adminID, !input.AllowWrites)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate impersonation token")
+		return
+	}
+
+	recordAudit(c, "impersonation.start", "user", target.ID, fmt.Sprintf("allow_writes=%t reason=%s", input.AllowWrites, input.Reason))
+	utils.SuccessResponse(c, http.StatusOK, "Impersonation started", gin.H{
+		"token":      token,
+		"expires_at": expiresAt,
+		"read_only":  !input.AllowWrites,
+		"user":       target,
+	})
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"fmt"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Integration endpoints are used by service accounts authenticated with an API key.
+// Access is governed by the key's scopes rather than course ownership.
+
+// integrationListOptions parses the pagination parameters of an integration
+// list. Pages hold the maximum page size unless ?limit= asks for fewer, since
+// syncing clients read whole lists.
+func integrationListOptions(c *gin.Context, sortable map[string]string) (utils.ListOptions, bool) {
+	opts, err := utils.ParseListOptions(c, sortable, "id")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return opts, false
+	}
+	if c.Query("limit") == "" {
+		opts.Limit = utils.MaxPageSize
+	}
+	return opts, true
+}
+
+// IntegrationListCourses returns a page of all courses (courses:read)
+func IntegrationListCourses(c *gin.Context) {
+	opts, ok := integrationListOptions(c, map[string]string{"id": "courses.id"})
+	if !ok {
+		return
+	}
+
+	courses, page, err := utils.Paginate(c, config.DB.WithContext(c.Request.Context()).Model(&models.Course{}), opts, "courses.id",
+		func(course models.Course) uint { return course.ID })
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch courses")
+		return
+	}
+
+	utils.PaginatedResponse(c, "Courses fetched successfully", courses, page)
+}
+
+// IntegrationListEnrollments returns a page of the enrollments of a course (enrollments:read)
+func IntegrationListEnrollments(c *gin.Context) {
+	courseID, err := strconv.Atoi(c.Param("courseId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+	opts, ok := integrationListOptions(c, map[string]string{"id": "enrollments.id"})
+	if !ok {
+		return
+	}
+
+	// Deleted students are still listed so past enrollments stay complete
+	query := config.DB.WithContext(c.Request.Context()).Model(&models.Enrollment{}).Preload("Student", unscoped).Where("course_id = ?", courseID)
+	enrollments, page, err := utils.Paginate(c, query, opts, "enrollments.id", func(enrollment models.Enrollment) uint { return enrollment.ID })
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch enrollments")
+		return
+	}
+
+	utils.PaginatedResponse(c, "Enrollments fetched successfully", enrollments, page)
+}
+
+// IntegrationEnrollStudent enrolls a student in any course (enrollments:write)
+func (h *Handlers) IntegrationEnrollStudent(c *gin.Context) {
+	var input EnrollStudentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	course, err := h.Courses.Find(c.Request.Context(), input.CourseID)
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to fetch course")
+		return
+	}
+
+	enrollment, err := h.Enrollments.Enroll(c.Request.Context(), course, input.StudentID)
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to enroll student")
+		return
+	}
+
+	recordAudit(c, "enrollment.create", "enrollment", enrollment.ID, fmt.Sprintf("student=%d course=%d", input.StudentID, input.CourseID))
+	utils.SuccessResponse(c, http.StatusCreated, "Student enrolled successfully", enrollment)
+}
+
+// IntegrationListGrades returns a page of the grades of a course (grades:read)
+func IntegrationListGrades(c *gin.Context) {
+	courseID, err := strconv.Atoi(c.Param("courseId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+	opts, ok := integrationListOptions(c, map[string]string{"id": "grades.id", "student_id": "grades.student_id"})
+	if !ok {
+		return
+	}
+
+	query := config.DB.WithContext(c.Request.Context()).Model(&models.Grade{}).Where("course_id = ?", courseID)
+	grades, page, err := utils.Paginate(c, query, opts, "grades.id", func(grade models.Grade) uint { return grade.ID })
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch grades")
+		return
+	}
+
+	utils.PaginatedResponse(c, "Grades fetched successfully", grades, page)
+}
+
+// IntegrationAddOrUpdateGrade grades an enrolled student in any course (grades:write)
+func (h *Handlers) IntegrationAddOrUpdateGrade(c *gin.Context) {
+	var input GradeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if grade, ok := h.saveGrade(c, input); ok {
+		recordAudit(c, "grade.write", "grade", grade.ID, fmt.Sprintf("student=%d course=%d marks=%.2f", grade.StudentID, grade.CourseID, grade.Marks))
+	}
+}
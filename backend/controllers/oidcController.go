@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"context"
+	"grade-management-system/config"
+	"grade-management-system/metrics"
+	"grade-management-system/models"
+	"grade-management-system/utils"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+const oidcSessionCookie = "oidc_session"
+
+// OIDCLogin starts the authorization-code flow by redirecting to the identity provider.
+// The state, nonce and PKCE verifier travel in a signed, short-lived cookie.
+func OIDCLogin(c *gin.Context) {
+	provider, err := utils.GetOIDCProvider(c.Request.Context())
+	if err != nil {
+		if _, enabled := utils.GetOIDCSettings(); !enabled {
+			utils.ErrorResponse(c, http.StatusNotFound, "Single sign-on is not configured")
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "OIDC discovery failed", "error", err)
+		utils.ErrorResponse(c, http.StatusBadGateway, "Identity provider is unavailable")
+		return
+	}
+
+	session := utils.NewOIDCSession()
+	cookie, err := utils.SignOIDCSession(session)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start login")
+		return
+	}
+
+	secure := strings.HasPrefix(provider.OAuth2.RedirectURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcSessionCookie, cookie, int(utils.OIDCSessionLifetime.Seconds()), "/auth/oidc", "", secure, true)
+
+	authURL := provider.OAuth2.AuthCodeURL(
+		session.State,
+		oidc.Nonce(session.Nonce),
+		oauth2.S256ChallengeOption(session.Verifier),
+	)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes the login: it exchanges the code, verifies the ID token,
+// maps the email claim to a user and issues the system's own JWT like Login does.
+func OIDCCallback(c *gin.Context) {
+	provider, err := utils.GetOIDCProvider(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Single sign-on is not configured")
+		return
+	}
+	defer func() { metrics.LoginAttempt(metrics.LoginSSO, c.Writer.Status() == http.StatusOK) }()
+
+	if idpError := c.Query("error"); idpError != "" {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Identity provider rejected the login: "+idpError)
+		return
+	}
+
+	cookie, err := c.Cookie(oidcSessionCookie)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Login session not found or expired")
+		return
+	}
+	// The session is single use
+	c.SetCookie(oidcSessionCookie, "", -1, "/auth/oidc", "", false, true)
+
+	session, err := utils.ParseOIDCSession(cookie)
+	if err != nil || c.Query("state") != session.State {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid login state")
+		return
+	}
+
+	oauthToken, err := provider.OAuth2.Exchange(c.Request.Context(), c.Query("code"), oauth2.VerifierOption(session.Verifier))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Failed to exchange authorization code")
+		return
+	}
+
+	rawIDToken, ok := oauthToken.Extra("id_token").(string)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Identity provider did not return an ID token")
+		return
+	}
+
+	idToken, err := provider.Verifier.Verify(c.Request.Context(), rawIDToken)
+	if err != nil || idToken.Nonce != session.Nonce {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid ID token")
+		return
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid ID token claims")
+		return
+	}
+
+	email, _ := claims[provider.Settings.EmailClaim].(string)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "ID token does not contain an email address")
+		return
+	}
+	// Claims such as upn or preferred_username are not always email addresses
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		utils.ErrorResponse(c, http.StatusBadRequest, "The "+provider.Settings.EmailClaim+" claim is not an email address")
+		return
+	}
+	// A missing email_verified claim counts as unverified
+	if verified, _ := claims["email_verified"].(bool); provider.Settings.RequireVerified && !verified {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Email address is not verified by the identity provider")
+		return
+	}
+
+	// Deleted accounts are looked up too so their email is never provisioned again
+	var user models.User
+	if err := config.DB.WithContext(c.Request.Context()).Unscoped().Where("email = ?", email).First(&user).Error; err != nil {
+		if !provider.Settings.CanProvision(email) {
+			utils.ErrorResponse(c, http.StatusForbidden, "No account is linked to this identity")
+			return
+		}
+
+		name, _ := claims["name"].(string)
+		if name == "" {
+			name, _, _ = strings.Cut(email, "@")
+		}
+		user, err = provisionSSOStudent(c.Request.Context(), name, email)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create user")
+			return
+		}
+	}
+
+	if user.DeletedAt.Valid {
+		utils.ErrorResponse(c, http.StatusForbidden, "No account is linked to this identity")
+		return
+	}
+	if user.DeactivatedAt != nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "Account is deactivated")
+		return
+	}
+
+	token, err := utils.GenerateToken(user.ID, user.Role)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Login successful", gin.H{
+		"token": token,
+		"user":  user,
+	})
+}
+
+// provisionSSOStudent creates a student for a first-time SSO login.
+// The password is random and never disclosed, so the account can only log in via SSO.
+func provisionSSOStudent(ctx context.Context, name, email string) (models.User, error) {
+	hashedPassword, err := utils.HashPassword(utils.RandomToken(32))
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		Name:     name,
+		Email:    email,
+		Password: hashedPassword,
+		Role:     "student",
+	}
+	if err := config.DB.WithContext(ctx).Create(&user).Error; err != nil {
+		return models.User{}, err
+	}
+
+	slog.Info("Provisioned student from SSO login", "student_id", user.ID)
+	return user, nil
+}
@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"grade-management-system/config"
+	"grade-management-system/migrations"
+	"grade-management-system/models"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	mockIdPClientID = "grade-api"
+	mockIdPKeyID    = "mock-key"
+)
+
+// mockIdP is a minimal OpenID Connect provider. It issues an ID token with
+// the claims set by the test, and only redeems a code for the PKCE verifier
+// matching the challenge sent to its authorization endpoint.
+type mockIdP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	claims map[string]interface{}
+	codes  map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	nonce     string
+	challenge string
+	claims    map[string]interface{}
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &mockIdP{key: key, codes: map[string]mockAuthorization{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/jwks", idp.jwks)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// login sets the claims of the next ID token, like choosing who signs in
+func (idp *mockIdP) login(claims map[string]interface{}) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.claims = claims
+}
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                idp.URL,
+		"authorization_endpoint":                idp.URL + "/authorize",
+		"token_endpoint":                        idp.URL + "/token",
+		"jwks_uri":                              idp.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (idp *mockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != mockIdPClientID || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "bad authorization request", http.StatusBadRequest)
+		return
+	}
+
+	idp.mu.Lock()
+	code := base64.RawURLEncoding.EncodeToString(big.NewInt(time.Now().UnixNano()).Bytes())
+	idp.codes[code] = mockAuthorization{nonce: query.Get("nonce"), challenge: query.Get("code_challenge"), claims: idp.claims}
+	idp.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	auth, ok := idp.codes[r.FormValue("code")]
+	delete(idp.codes, r.FormValue("code"))
+	idp.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != auth.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   idp.URL,
+		"aud":   mockIdPClientID,
+		"sub":   "subject",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Minute).Unix(),
+		"nonce": auth.nonce,
+	}
+	for name, value := range auth.claims {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = mockIdPKeyID
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": mockIdPKeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}},
+	})
+}
+
+// setupSSO serves the SSO routes against an in-memory database and a mock
+// IdP that auto-provisions students of university.edu
+func setupSSO(t *testing.T) (*mockIdP, *httptest.Server) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := config.OpenSQLite(config.SQLiteMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.EnsureDefaultRoles(db); err != nil {
+		t.Fatal(err)
+	}
+	config.DB = db
+
+	r := gin.New()
+	r.GET("/auth/oidc/login", OIDCLogin)
+	r.GET("/auth/oidc/callback", OIDCCallback)
+	api := httptest.NewServer(r)
+	t.Cleanup(api.Close)
+
+	idp := newMockIdP(t)
+	t.Setenv("JWT_EPHEMERAL_KEY", "true")
+	t.Setenv("OIDC_ISSUER_URL", idp.URL)
+	t.Setenv("OIDC_CLIENT_ID", mockIdPClientID)
+	t.Setenv("OIDC_CLIENT_SECRET", "secret")
+	t.Setenv("OIDC_REDIRECT_URL", api.URL+"/auth/oidc/callback")
+	t.Setenv("OIDC_AUTO_PROVISION", "true")
+	t.Setenv("OIDC_PROVISION_DOMAINS", "university.edu")
+	return idp, api
+}
+
+// ssoLogin follows the redirects from /auth/oidc/login through the IdP back
+// to the callback, like a browser, and returns the callback's response
+func ssoLogin(t *testing.T, api *httptest.Server) (int, map[string]interface{}) {
+	t.Helper()
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+
+	resp, err := client.Get(api.URL + "/auth/oidc/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp, api := setupSSO(t)
+
+	teacher := models.User{Name: "Anjali Desai", Email: "anjali.desai@university.edu", Password: "unused", Role: models.RoleTeacher}
+	if err := config.DB.Create(&teacher).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("existing user", func(t *testing.T) {
+		idp.login(map[string]interface{}{"email": "Anjali.Desai@university.edu", "email_verified": true})
+		status, body := ssoLogin(t, api)
+		if status != http.StatusOK {
+			t.Fatalf("got %d %v, want 200", status, body)
+		}
+		user := body["data"].(map[string]interface{})["user"].(map[string]interface{})
+		if uint(user["id"].(float64)) != teacher.ID {
+			t.Errorf("logged in as user %v, want %d", user["id"], teacher.ID)
+		}
+	})
+
+	t.Run("provisions a student in an allowed domain", func(t *testing.T) {
+		idp.login(map[string]interface{}{"email": "new.student@university.edu", "email_verified": true})
+		status, body := ssoLogin(t, api)
+		if status != http.StatusOK {
+			t.Fatalf("got %d %v, want 200", status, body)
+		}
+
+		var user models.User
+		if err := config.DB.Where("email = ?", "new.student@university.edu").First(&user).Error; err != nil {
+			t.Fatalf("student was not provisioned: %v", err)
+		}
+		if user.Role != models.RoleStudent || user.Name != "new.student" {
+			t.Errorf("provisioned %q with role %q, want new.student with role student", user.Name, user.Role)
+		}
+	})
+
+	rejected := []struct {
+		name   string
+		claims map[string]interface{}
+		status int
+	}{
+		{"domain outside the allow list", map[string]interface{}{"email": "someone@gmail.com", "email_verified": true}, http.StatusForbidden},
+		{"unverified email", map[string]interface{}{"email": "anjali.desai@university.edu", "email_verified": false}, http.StatusUnauthorized},
+		{"missing email_verified", map[string]interface{}{"email": "anjali.desai@university.edu"}, http.StatusUnauthorized},
+		{"claim that is not an email", map[string]interface{}{"email": "UNIVERSITY\\adesai", "email_verified": true}, http.StatusBadRequest},
+	}
+	for _, tc := range rejected {
+		t.Run(tc.name, func(t *testing.T) {
+			idp.login(tc.claims)
+			if status, body := ssoLogin(t, api); status != tc.status {
+				t.Errorf("got %d %v, want %d", status, body, tc.status)
+			}
+		})
+	}
+
+	t.Run("forged state", func(t *testing.T) {
+		idp.login(map[string]interface{}{"email": "new.student@university.edu", "email_verified": true})
+
+		jar, _ := cookiejar.New(nil)
+		client := &http.Client{
+			Jar: jar,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				// Stop before the callback to tamper with the state
+				if strings.HasPrefix(req.URL.String(), api.URL+"/auth/oidc/callback") {
+					return http.ErrUseLastResponse
+				}
+				return nil
+			},
+		}
+		resp, err := client.Get(api.URL + "/auth/oidc/login")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		callback, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil || callback.Query().Get("code") == "" {
+			t.Fatalf("IdP did not redirect back with a code: %q", resp.Header.Get("Location"))
+		}
+		query := callback.Query()
+		query.Set("state", "forged")
+		callback.RawQuery = query.Encode()
+
+		resp, err = client.Get(callback.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("got %d, want 400 for a forged state", resp.StatusCode)
+		}
+	})
+
+	var users int64
+	config.DB.Model(&models.User{}).Count(&users)
+	if users != 2 {
+		t.Errorf("%d users exist, want only the teacher and the provisioned student", users)
+	}
+}
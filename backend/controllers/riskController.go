@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/risk"
+	"grade-management-system/utils"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// reevaluateRisk queues students whose scores or attendance just changed for
+// their early-warning flags to be refreshed after the response
+func reevaluateRisk(studentIDs ...uint) {
+	risk.Enqueue(studentIDs...)
+}
+
+// AtRiskFlag is one reason a student is at risk
+type AtRiskFlag struct {
+	ID         uint      `json:"id"`
+	Rule       string    `json:"rule"`
+	CourseID   *uint     `json:"course_id"`
+	CourseName *string   `json:"course_name"`
+	Reason     string    `json:"reason"`
+	RaisedAt   time.Time `json:"raised_at"`
+}
+
+// AtRiskStudent is a student with their active early-warning flags
+type AtRiskStudent struct {
+	StudentID uint         `json:"student_id"`
+	Name      string       `json:"name"`
+	Email     string       `json:"email"`
+	Flags     []AtRiskFlag `json:"flags"`
+}
+
+// TeacherListAtRisk lists the at-risk students in the teacher's courses: flags
+// raised in those courses, and term-wide flags of the students enrolled in them
+func TeacherListAtRisk(c *gin.Context) {
+	teacherID := c.MustGet("userID").(uint)
+
+	courses := config.DB.WithContext(c.Request.Context()).Model(&models.Course{}).Select("id").Where("teacher_id = ? AND archived_at IS NULL", teacherID)
+	students := config.DB.WithContext(c.Request.Context()).Model(&models.Enrollment{}).Select("student_id").Where("course_id IN (?)", courses)
+	query := activeRiskFlags(c.Request.Context()).Where("(risk_flags.course_id IN (?) OR (risk_flags.course_id IS NULL AND risk_flags.student_id IN (?)))", courses, students)
+	if courseID := c.Query("course_id"); courseID != "" {
+		query = query.Where("risk_flags.course_id = ?", courseID)
+	}
+
+	respondWithAtRisk(c, query)
+}
+
+// AdminListAtRisk lists every at-risk student, optionally filtered by
+// ?course_id=, ?rule= and ?department=
+func AdminListAtRisk(c *gin.Context) {
+	query := activeRiskFlags(c.Request.Context())
+	if courseID := c.Query("course_id"); courseID != "" {
+		query = query.Where("risk_flags.course_id = ?", courseID)
+	}
+	if rule := c.Query("rule"); rule != "" {
+		query = query.Where("risk_flags.rule_key = ?", rule)
+	}
+	if department := c.Query("department"); department != "" {
+		query = query.Where("users.department = ?", department)
+	}
+
+	respondWithAtRisk(c, query)
+}
+
+// activeRiskFlags selects the unresolved flags of active students
+func activeRiskFlags(ctx context.Context) *gorm.DB {
+	return config.DB.WithContext(ctx).Table("risk_flags").
+		Select(`risk_flags.id, risk_flags.student_id, users.name AS student_name, users.email AS student_email,
+			risk_flags.course_id, courses.name AS course_name, risk_flags.rule_key, risk_flags.reason, risk_flags.raised_at`).
+		Joins("JOIN users ON users.id = risk_flags.student_id AND users.deleted_at IS NULL AND users.deactivated_at IS NULL").
+		Joins("LEFT JOIN courses ON courses.id = risk_flags.course_id").
+		Where("risk_flags.resolved_at IS NULL")
+}
+
+// respondWithAtRisk groups the selected flags by student
+func respondWithAtRisk(c *gin.Context, query *gorm.DB) {
+	var rows []struct {
+		ID           uint
+		StudentID    uint
+		StudentName  string
+		StudentEmail string
+		CourseID     *uint
+		CourseName   *string
+		RuleKey      string
+		Reason       string
+		RaisedAt     time.Time
+	}
+	if err := query.Order("users.name, users.id, risk_flags.raised_at").Scan(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch at-risk students")
+		return
+	}
+
+	students := []AtRiskStudent{}
+	for _, row := range rows {
+		if len(students) == 0 || students[len(students)-1].StudentID != row.StudentID {
+			students = append(students, AtRiskStudent{StudentID: row.StudentID, Name: row.StudentName, Email: row.StudentEmail})
+		}
+		student := &students[len(students)-1]
+		student.Flags = append(student.Flags, AtRiskFlag{
+			ID: row.ID, Rule: row.RuleKey, CourseID: row.CourseID, CourseName: row.CourseName,
+			Reason: row.Reason, RaisedAt: row.RaisedAt,
+		})
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "At-risk students fetched successfully", students)
+}
+
+// ListRiskRules returns the early-warning rules and their thresholds
+func ListRiskRules(c *gin.Context) {
+	var rules []models.RiskRule
+	if err := config.DB.WithContext(c.Request.Context()).Order("id").Find(&rules).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch risk rules")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Risk rules fetched successfully", rules)
+}
+
+// UpdateRiskRuleInput holds the rule settings an admin may change; omitted fields are left as they are
+type UpdateRiskRuleInput struct {
+	Enabled   *bool    `json:"enabled"`
+	Threshold *float64 `json:"threshold" binding:"omitempty,min=0"`
+	MinCount  *int     `json:"min_count" binding:"omitempty,min=0,max=1000"`
+}
+
+// UpdateRiskRule changes a rule's thresholds or turns it on or off, then
+// re-evaluates every student so the at-risk lists reflect it straight away
+func UpdateRiskRule(c *gin.Context) {
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid risk rule ID")
+		return
+	}
+
+	var rule models.RiskRule
+	if err := config.DB.WithContext(c.Request.Context()).First(&rule, ruleID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Risk rule not found")
+		return
+	}
+
+	var input UpdateRiskRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var changes []string
+	if input.Enabled != nil {
+		rule.Enabled = *input.Enabled
+		changes = append(changes, fmt.Sprintf("enabled=%t", rule.Enabled))
+	}
+	if input.Threshold != nil {
+		limit := 100.0 // Percentages
+		if rule.Key == models.RiskRuleGPADrop {
+			limit = 4 // GPA points
+		}
+		if *input.Threshold > limit {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("threshold of %s must be at most %g", rule.Key, limit))
+			return
+		}
+		rule.Threshold = *input.Threshold
+		changes = append(changes, "threshold="+strconv.FormatFloat(rule.Threshold, 'f', -1, 64))
+	}
+	if input.MinCount != nil {
+		rule.MinCount = *input.MinCount
+		changes = append(changes, fmt.Sprintf("min_count=%d", rule.MinCount))
+	}
+
+	if err := config.DB.WithContext(c.Request.Context()).Model(&rule).Select("Enabled", "Threshold", "MinCount").Updates(&rule).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update risk rule")
+		return
+	}
+	recordAudit(c, "risk_rule.update", "risk_rule", rule.ID, strings.Join(changes, ", "))
+
+	if err := risk.Evaluate(c.Request.Context(), config.DB, nil); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Risk evaluation after updating rule failed", "rule", rule.Key, "error", err)
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Risk rule updated successfully", rule)
+}
+
+// EvaluateRiskRules re-evaluates every student immediately instead of waiting for the schedule
+func EvaluateRiskRules(c *gin.Context) {
+	if err := risk.Evaluate(c.Request.Context(), config.DB, nil); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Risk evaluation failed", "error", err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to evaluate risk rules")
+		return
+	}
+
+	var active int64
+	config.DB.WithContext(c.Request.Context()).Model(&models.RiskFlag{}).Where("resolved_at IS NULL").Count(&active)
+	utils.SuccessResponse(c, http.StatusOK, "Risk rules evaluated", gin.H{"active_flags": active})
+}
@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListPermissions returns every permission that can be granted to a role
+func ListPermissions(c *gin.Context) {
+	var permissions []models.Permission
+	if err := config.DB.WithContext(c.Request.Context()).Order("name").Find(&permissions).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch permissions")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Permissions fetched successfully", permissions)
+}
+
+// ListRoles returns all roles with their permissions
+func ListRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := config.DB.WithContext(c.Request.Context()).Preload("Permissions").Order("id").Find(&roles).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch roles")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Roles fetched successfully", roles)
+}
+
+type RoleInput struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required,min=1"`
+}
+
+// CreateRole defines a custom role as a named set of permissions
+func CreateRole(c *gin.Context) {
+	var input RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	permissions, err := findPermissions(c.Request.Context(), input.Permissions)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role := models.Role{
+		Name:        strings.ToLower(strings.TrimSpace(input.Name)),
+		Description: input.Description,
+		Permissions: permissions,
+	}
+
+	if err := config.DB.WithContext(c.Request.Context()).Create(&role).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create role. Ensure the role name is unique.")
+		return
+	}
+
+	recordAudit(c, "role.create", "role", role.ID, strings.Join(input.Permissions, " "))
+	utils.SuccessResponse(c, http.StatusCreated, "Role created successfully", role)
+}
+
+type UpdateRoleInput struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required,min=1"`
+}
+
+// UpdateRole replaces the permissions of a custom role
+func UpdateRole(c *gin.Context) {
+	role, ok := findCustomRole(c)
+	if !ok {
+		return
+	}
+
+	var input UpdateRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	permissions, err := findPermissions(c.Request.Context(), input.Permissions)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = config.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		role.Description = input.Description
+		if err := tx.Save(&role).Error; err != nil {
+			return err
+		}
+		return tx.Model(&role).Association("Permissions").Replace(permissions)
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update role")
+		return
+	}
+
+	recordAudit(c, "role.update", "role", role.ID, strings.Join(input.Permissions, " "))
+	utils.SuccessResponse(c, http.StatusOK, "Role updated successfully", role)
+}
+
+// DeleteRole removes a custom role and all of its assignments
+func DeleteRole(c *gin.Context) {
+	role, ok := findCustomRole(c)
+	if !ok {
+		return
+	}
+
+	var primaryUsers int64
+	if err := config.DB.WithContext(c.Request.Context()).Model(&models.User{}).Where("role = ?", role.Name).Count(&primaryUsers).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete role")
+		return
+	}
+	if primaryUsers > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "Role is the primary role of existing users")
+		return
+	}
+
+	err := config.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM user_roles WHERE role_id = ?", role.ID).Error; err != nil {
+			return err
+		}
+		return tx.Select("Permissions").Delete(&role).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete role")
+		return
+	}
+
+	recordAudit(c, "role.delete", "role", role.ID, role.Name)
+	utils.SuccessResponse(c, http.StatusOK, "Role deleted successfully", nil)
+}
+
+type AssignRolesInput struct {
+	Roles []string `json:"roles" binding:"required"`
+}
+
+// AssignUserRoles replaces the additional roles of a user. The primary role in
+// users.role is unchanged and always applies.
+func AssignUserRoles(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var input AssignRolesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := config.DB.WithContext(c.Request.Context()).First(&user, userID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	roles := []models.Role{}
+	if len(input.Roles) > 0 {
+		if err := config.DB.WithContext(c.Request.Context()).Where("name IN ?", input.Roles).Find(&roles).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch roles")
+			return
+		}
+		if len(roles) != len(input.Roles) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "One or more roles do not exist")
+			return
+		}
+	}
+
+	// The assistant role follows the course_assistants rows, so it is only
+	// removed through RemoveAssistant
+	keepsAssistantRole := false
+	for _, role := range roles {
+		if role.Name == models.RoleTeachingAssistant {
+			keepsAssistantRole = true
+		}
+	}
+	if !keepsAssistantRole {
+		var assisting int64
+		if err := config.DB.WithContext(c.Request.Context()).Model(&models.CourseAssistant{}).Where("user_id = ?", user.ID).Count(&assisting).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to check course assistants")
+			return
+		}
+		if assisting > 0 {
+			utils.ErrorResponse(c, http.StatusConflict, fmt.Sprintf("User assists in %d course(s); keep the %s role or remove them as assistant first", assisting, models.RoleTeachingAssistant))
+			return
+		}
+	}
+
+	if err := config.DB.WithContext(c.Request.Context()).Model(&user).Association("Roles").Replace(roles); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to assign roles")
+		return
+	}
+
+	recordAudit(c, "user.roles.assign", "user", user.ID, strings.Join(input.Roles, " "))
+	utils.SuccessResponse(c, http.StatusOK, "Roles assigned successfully", user)
+}
+
+// findPermissions loads permissions by name and rejects unknown names
+func findPermissions(ctx context.Context, names []string) ([]models.Permission, error) {
+	var permissions []models.Permission
+	if err := config.DB.WithContext(ctx).Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		found := false
+		for _, permission := range permissions {
+			if permission.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown permission %q", name)
+		}
+	}
+	return permissions, nil
+}
+
+// findCustomRole loads the role from the :id path parameter, refusing built-in roles
+func findCustomRole(c *gin.Context) (models.Role, bool) {
+	var role models.Role
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid role ID")
+		return role, false
+	}
+
+	if err := config.DB.WithContext(c.Request.Context()).First(&role, roleID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Role not found")
+		return role, false
+	}
+
+	if role.BuiltIn {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Built-in roles cannot be modified; create a custom role instead")
+		return role, false
+	}
+	return role, true
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"fmt"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateServiceAccountInput struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// CreateServiceAccount registers a new integration identity
+func CreateServiceAccount(c *gin.Context) {
+	adminID := c.MustGet("userID").(uint)
+
+	var input CreateServiceAccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	account := models.ServiceAccount{
+		Name:        input.Name,
+		Description: input.Description,
+		CreatedByID: adminID,
+	}
+
+	if err := config.DB.WithContext(c.Request.Context()).Create(&account).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create service account. Ensure the name is unique.")
+		return
+	}
+
+	recordAudit(c, "service_account.create", "service_account", account.ID, account.Name)
+	utils.SuccessResponse(c, http.StatusCreated, "Service account created successfully", account)
+}
+
+// ListServiceAccounts returns every service account with its keys (hashes are never exposed)
+func ListServiceAccounts(c *gin.Context) {
+	var accounts []models.ServiceAccount
+	if err := config.DB.WithContext(c.Request.Context()).Preload("APIKeys").Order("id").Find(&accounts).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch service accounts")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Service accounts fetched successfully", accounts)
+}
+
+type CreateAPIKeyInput struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+// CreateAPIKey issues a new key for a service account. The full key is only
+// returned in this response; afterwards just its prefix is shown.
+func CreateAPIKey(c *gin.Context) {
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid service account ID")
+		return
+	}
+
+	var input CreateAPIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, scope := range input.Scopes {
+		if !isValidScope(scope) {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Unknown scope %q. Valid scopes: %s", scope, strings.Join(models.ValidScopes, ", ")))
+			return
+		}
+	}
+
+	var account models.ServiceAccount
+	if err := config.DB.WithContext(c.Request.Context()).First(&account, accountID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Service account not found")
+		return
+	}
+
+	if input.ExpiresInDays == 0 {
+		input.ExpiresInDays = 90
+	}
+
+	key, prefix, hash := utils.GenerateAPIKey()
+	apiKey := models.APIKey{
+		ServiceAccountID: account.ID,
+		Name:             input.Name,
+		Prefix:           prefix,
+		KeyHash:          hash,
+		Scopes:           strings.Join(input.Scopes, " "),
+		ExpiresAt:        time.Now().AddDate(0, 0, input.ExpiresInDays),
+	}
+
+	if err := config.DB.WithContext(c.Request.Context()).Create(&apiKey).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	recordAudit(c, "api_key.create", "api_key", apiKey.ID, fmt.Sprintf("service_account=%d scopes=%s", account.ID, apiKey.Scopes))
+	utils.SuccessResponse(c, http.StatusCreated, "API key created. Store it now, it will not be shown again.", gin.H{
+		"key":     key,
+		"api_key": apiKey,
+	})
+}
+
+// RevokeAPIKey immediately invalidates a key
+func RevokeAPIKey(c *gin.Context) {
+	keyID, err := strconv.Atoi(c.Param("keyId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	var apiKey models.APIKey
+	if err := config.DB.WithContext(c.Request.Context()).First(&apiKey, keyID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	if apiKey.RevokedAt == nil {
+		now := time.Now()
+		apiKey.RevokedAt = &now
+		if err := config.DB.WithContext(c.Request.Context()).Save(&apiKey).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke API key")
+			return
+		}
+		recordAudit(c, "api_key.revoke", "api_key", apiKey.ID, "")
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "API key revoked", apiKey)
+}
+
+// auditLogSortColumns are the sort keys accepted by ListAuditLogs
+var auditLogSortColumns = map[string]string{
+	"id": "audit_logs.id",
+}
+
+// ListAuditLogs returns a page of audit entries, newest first by default,
+// optionally filtered by actor or impersonator
+func ListAuditLogs(c *gin.Context) {
+	opts, err := utils.ParseListOptions(c, auditLogSortColumns, "-id")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := config.DB.WithContext(c.Request.Context()).Model(&models.AuditLog{})
+	if actorType := c.Query("actor_type"); actorType != "" {
+		query = query.Where("actor_type = ?", actorType)
+	}
+	for _, filter := range []string{"actor_id", "impersonator_id"} {
+		value := c.Query(filter)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, filter+" must be a positive integer")
+			return
+		}
+		query = query.Where(filter+" = ?", id)
+	}
+
+	entries, page, err := utils.Paginate(c, query, opts, "audit_logs.id", func(entry models.AuditLog) uint { return entry.ID })
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch audit logs")
+		return
+	}
+
+	utils.PaginatedResponse(c, "Audit logs fetched successfully", entries, page)
+}
+
+func isValidScope(scope string) bool {
+	for _, valid := range models.ValidScopes {
+		if scope == valid {
+			return true
+		}
+	}
+	return false
+}
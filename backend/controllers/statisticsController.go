@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"fmt"
+	"grade-management-system/utils"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Histogram bin counts accepted by ?bins=
+const (
+	defaultHistogramBins = 10
+	maxHistogramBins     = 50
+)
+
+// GetGradeStatistics returns the distribution of final marks in the teacher's
+// course: counts per grade letter, mean, spread, quartiles, pass rate and a
+// histogram with ?bins= equal-width bins (10 by default), plus the same figures
+// for each assessment component
+func (h *Handlers) GetGradeStatistics(c *gin.Context) {
+	course, ok := authorizeCourse(c, abilityOwner)
+	if !ok {
+		return
+	}
+
+	bins := defaultHistogramBins
+	if value := c.Query("bins"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxHistogramBins {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("bins must be between 1 and %d", maxHistogramBins))
+			return
+		}
+		bins = parsed
+	}
+
+	stats, err := h.Grades.CourseStatistics(c.Request.Context(), course.ID, bins)
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to compute statistics")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Grade statistics retrieved", stats)
+}
+
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
+
+func roundedPtr(value float64) *float64 {
+	rounded := roundTo(value, 2)
+	return &rounded
+}
@@ -1,8 +1,6 @@
 package controllers
 
 import (
-	"grade-management-system/config"
-	"grade-management-system/models"
 	"grade-management-system/utils"
 	"net/http"
 
@@ -10,73 +8,62 @@ import (
 )
 
 // GetStudentCourses returns courses the student is enrolled in
-func GetStudentCourses(c *gin.Context) {
+func (h *Handlers) GetStudentCourses(c *gin.Context) {
 	studentID := c.MustGet("userID").(uint)
 
-	var enrollments []models.Enrollment
-	if err := config.DB.Preload("Course").Where("student_id = ?", studentID).Find(&enrollments).Error; err != nil {
+	courses, err := h.Enrollments.StudentCourses(c.Request.Context(), studentID)
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch enrolled courses")
 		return
 	}
 
-	// Extract courses from enrollments for a cleaner response
-	var courses []models.Course
-	for _, e := range enrollments {
-		courses = append(courses, e.Course)
-	}
-
 	utils.SuccessResponse(c, http.StatusOK, "Enrolled courses retrieved", courses)
 }
 
-// GetStudentGrades returns all grades for the student
-func GetStudentGrades(c *gin.Context) {
+// GetStudentGrades returns all grades for the student, with class comparisons
+// when ?compare=true
+func (h *Handlers) GetStudentGrades(c *gin.Context) {
 	studentID := c.MustGet("userID").(uint)
 
-	var grades []models.Grade
-	if err := config.DB.Preload("Course").Where("student_id = ?", studentID).Find(&grades).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch grades")
-		return
-	}
-
-	utils.SuccessResponse(c, http.StatusOK, "Grades retrieved", grades)
+	h.respondWithGrades(c, studentID)
 }
 
 // GetStudentGPA calculates and returns the student's GPA
 // A=4, B=3, C=2, D=1, F=0
-func GetStudentGPA(c *gin.Context) {
+func (h *Handlers) GetStudentGPA(c *gin.Context) {
 	studentID := c.MustGet("userID").(uint)
 
-	var grades []models.Grade
-	if err := config.DB.Where("student_id = ?", studentID).Find(&grades).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch grades for GPA calculation")
+	h.respondWithGPA(c, studentID)
+}
+
+// GetStudentAttendance returns the student's attendance summary per course
+func GetStudentAttendance(c *gin.Context) {
+	studentID := c.MustGet("userID").(uint)
+
+	summary, err := fetchAttendanceSummary(c.Request.Context(), studentID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch attendance")
 		return
 	}
 
-	if len(grades) == 0 {
-		utils.SuccessResponse(c, http.StatusOK, "No grades available to calculate GPA", gin.H{"gpa": 0.0})
+	utils.SuccessResponse(c, http.StatusOK, "Attendance retrieved", summary)
+}
+
+// respondWithGPA writes the GPA response shared by the student and guardian endpoints
+func (h *Handlers) respondWithGPA(c *gin.Context, studentID uint) {
+	gpa, courses, err := h.Grades.GPA(c.Request.Context(), studentID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch grades for GPA calculation")
 		return
 	}
 
-	totalPoints := 0.0
-	for _, grade := range grades {
-		switch grade.GradeLetter {
-		case "A":
-			totalPoints += 4
-		case "B":
-			totalPoints += 3
-		case "C":
-			totalPoints += 2
-		case "D":
-			totalPoints += 1
-		case "F":
-			totalPoints += 0
-		}
+	if courses == 0 {
+		utils.SuccessResponse(c, http.StatusOK, "No grades available to calculate GPA", gin.H{"gpa": 0.0})
+		return
 	}
 
-	gpa := totalPoints / float64(len(grades))
-
 	utils.SuccessResponse(c, http.StatusOK, "GPA calculated successfully", gin.H{
 		"gpa":           gpa,
-		"courses_count": len(grades),
+		"courses_count": courses,
 	})
 }
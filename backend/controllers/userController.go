@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"grade-management-system/models"
+	"grade-management-system/services"
+	"grade-management-system/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UpdateUserInput holds the profile fields an admin may change; omitted fields are left as they are
+type UpdateUserInput struct {
+	Name  *string `json:"name" binding:"omitempty,min=1"`
+	Email *string `json:"email" binding:"omitempty,email"`
+	Role  *string `json:"role" binding:"omitempty,oneof=teacher student guardian"`
+}
+
+// UpdateUser edits a user's name, email or primary role
+func (h *Handlers) UpdateUser(c *gin.Context) {
+	user, ok := h.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	var input UpdateUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	changes, err := h.Users.Update(c.Request.Context(), &user, services.UserUpdate{Name: input.Name, Email: input.Email, Role: input.Role})
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to update user")
+		return
+	}
+	if len(changes) == 0 {
+		utils.SuccessResponse(c, http.StatusOK, "Nothing to update", user)
+		return
+	}
+
+	recordAudit(c, "user.update", "user", user.ID, strings.Join(changes, ", "))
+	utils.SuccessResponse(c, http.StatusOK, "User updated successfully", user)
+}
+
+// DeactivateUser blocks a user from logging in. Existing tokens stop working
+// immediately because AuthRequired checks the account on every request.
+func (h *Handlers) DeactivateUser(c *gin.Context) {
+	user, ok := h.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	changed, err := h.Users.Deactivate(c.Request.Context(), &user, c.MustGet("userID").(uint))
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to deactivate user")
+		return
+	}
+	if changed {
+		recordAudit(c, "user.deactivate", "user", user.ID, "")
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "User deactivated", user)
+}
+
+// ReactivateUser lets a deactivated user log in again
+func (h *Handlers) ReactivateUser(c *gin.Context) {
+	user, ok := h.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	changed, err := h.Users.Reactivate(c.Request.Context(), &user)
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to reactivate user")
+		return
+	}
+	if changed {
+		recordAudit(c, "user.reactivate", "user", user.ID, "")
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "User reactivated", user)
+}
+
+// DeleteUser soft-deletes a user. The row is kept so grades, enrollments and
+// history that reference it stay intact, but the account can no longer be used.
+func (h *Handlers) DeleteUser(c *gin.Context) {
+	user, ok := h.findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Users.Delete(c.Request.Context(), &user, c.MustGet("userID").(uint)); err != nil {
+		respondWithServiceError(c, err, "Failed to delete user")
+		return
+	}
+
+	recordAudit(c, "user.delete", "user", user.ID, user.Email)
+	utils.SuccessResponse(c, http.StatusOK, "User deleted successfully", nil)
+}
+
+// findManagedUser loads the user from the :id path parameter, writing a 404 if it doesn't exist
+func (h *Handlers) findManagedUser(c *gin.Context) (models.User, bool) {
+	id, ok := idParam(c, "id", "User not found")
+	if !ok {
+		return models.User{}, false
+	}
+
+	user, err := h.Users.Find(c.Request.Context(), id)
+	if err != nil {
+		respondWithServiceError(c, err, "Failed to fetch user")
+		return user, false
+	}
+	return user, true
+}
+
+// unscoped is a Preload condition that includes soft-deleted users
+func unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
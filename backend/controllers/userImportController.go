@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/utils"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// importableRoles are the roles an admin may assign through an import, as with CreateUser
+var importableRoles = []string{models.RoleTeacher, models.RoleStudent, models.RoleGuardian}
+
+// ImportRowResult reports the validation outcome of one CSV row
+type ImportRowResult struct {
+	Row    int      `json:"row"` // Line number in the file
+	Email  string   `json:"email"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ImportedUser is a created account with the temporary password to hand to its owner
+type ImportedUser struct {
+	ID                uint   `json:"id"`
+	Name              string `json:"name"`
+	Email             string `json:"email"`
+	Role              string `json:"role"`
+	TemporaryPassword string `json:"temporary_password"`
+}
+
+// ImportUsers creates users from an uploaded CSV with the columns name, email,
+// role and optionally roll_number and department. Every row is validated first;
+// if any row fails nothing is imported. With ?dry_run=true only the validation
+// report is returned. Each user gets a generated temporary password that must
+// be changed on first login.
+func ImportUsers(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "A CSV file is required in the 'file' field")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to read the uploaded file")
+		return
+	}
+	defer file.Close()
+
+	sheet, err := utils.ReadCSV(file)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid CSV: "+err.Error())
+		return
+	}
+
+	for _, column := range []string{"name", "email", "role"} {
+		if sheet.Column(column) < 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Missing required column %q", column))
+			return
+		}
+	}
+	if len(sheet.Rows) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "The file contains no users")
+		return
+	}
+
+	users, results, invalid, err := validateUserImport(c.Request.Context(), sheet)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to check existing users")
+		return
+	}
+	summary := gin.H{
+		"dry_run": dryRun,
+		"total":   len(results),
+		"valid":   len(results) - invalid,
+		"invalid": invalid,
+		"rows":    results,
+	}
+
+	if invalid > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": fmt.Sprintf("%d of %d rows have errors; nothing was imported", invalid, len(results)),
+			"data":  summary,
+		})
+		return
+	}
+	if dryRun {
+		utils.SuccessResponse(c, http.StatusOK, fmt.Sprintf("All %d rows are valid", len(results)), summary)
+		return
+	}
+
+	// Hashing is slow, so it happens before the transaction to keep it short
+	passwords := make([]string, len(users))
+	for i := range users {
+		passwords[i] = utils.RandomToken(12)
+		hashedPassword, err := utils.HashPassword(passwords[i])
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to import users, nothing was imported")
+			return
+		}
+		users[i].Password = hashedPassword
+		users[i].MustChangePassword = true
+	}
+
+	err = config.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&users, 100).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to import users, nothing was imported")
+		return
+	}
+
+	imported := make([]ImportedUser, 0, len(users))
+	for i, user := range users {
+		imported = append(imported, ImportedUser{
+			ID:                user.ID,
+			Name:              user.Name,
+			Email:             user.Email,
+			Role:              user.Role,
+			TemporaryPassword: passwords[i],
+		})
+	}
+
+	recordAudit(c, "user.import", "user", 0, fmt.Sprintf("%d users", len(imported)))
+	summary["users"] = imported
+	utils.SuccessResponse(c, http.StatusCreated, fmt.Sprintf("%d users imported. Share each temporary password with its owner; it will not be shown again.", len(imported)), summary)
+}
+
+// validateUserImport checks every row of an import, including duplicates within
+// the file and against existing (also deleted) accounts. It returns the users
+// to create, the per-row results and the number of invalid rows.
+func validateUserImport(ctx context.Context, sheet *utils.Sheet) ([]models.User, []ImportRowResult, int, error) {
+	nameCol, emailCol, roleCol := sheet.Column("name"), sheet.Column("email"), sheet.Column("role")
+	rollCol, departmentCol := sheet.Column("roll_number"), sheet.Column("department")
+
+	var emails, rollNumbers []string
+	for _, row := range sheet.Rows {
+		emails = append(emails, strings.ToLower(sheet.Cell(row, emailCol)))
+		if roll := sheet.Cell(row, rollCol); roll != "" {
+			rollNumbers = append(rollNumbers, roll)
+		}
+	}
+
+	takenEmails := map[string]bool{}
+	takenRollNumbers := map[string]bool{}
+	var existing []models.User
+	// Stored emails are not guaranteed to be lowercase
+	if err := config.DB.WithContext(ctx).Unscoped().Select("email", "roll_number").
+		Where("LOWER(email) IN ? OR roll_number IN ?", emails, rollNumbers).Find(&existing).Error; err != nil {
+		return nil, nil, 0, err
+	}
+	for _, user := range existing {
+		takenEmails[strings.ToLower(user.Email)] = true
+		if user.RollNumber != nil {
+			takenRollNumbers[*user.RollNumber] = true
+		}
+	}
+
+	seenEmails := map[string]int{}
+	seenRollNumbers := map[string]int{}
+	users := make([]models.User, 0, len(sheet.Rows))
+	results := make([]ImportRowResult, 0, len(sheet.Rows))
+	invalid := 0
+
+	for i, row := range sheet.Rows {
+		line := sheet.Line[i]
+		user := models.User{
+			Name:       sheet.Cell(row, nameCol),
+			Email:      strings.ToLower(sheet.Cell(row, emailCol)),
+			Role:       strings.ToLower(sheet.Cell(row, roleCol)),
+			Department: sheet.Cell(row, departmentCol),
+		}
+		result := ImportRowResult{Row: line, Email: user.Email}
+
+		if user.Name == "" {
+			result.Errors = append(result.Errors, "name is required")
+		}
+
+		if user.Email == "" {
+			result.Errors = append(result.Errors, "email is required")
+		} else if address, err := mail.ParseAddress(user.Email); err != nil || address.Address != user.Email {
+			result.Errors = append(result.Errors, "email is not a valid address")
+		} else if takenEmails[user.Email] {
+			result.Errors = append(result.Errors, "email is already in use")
+		} else if first, ok := seenEmails[user.Email]; ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("email duplicates row %d", first))
+		} else {
+			seenEmails[user.Email] = line
+		}
+
+		if !containsString(importableRoles, user.Role) {
+			result.Errors = append(result.Errors, "role must be one of "+strings.Join(importableRoles, ", "))
+		}
+
+		if roll := sheet.Cell(row, rollCol); roll != "" {
+			if takenRollNumbers[roll] {
+				result.Errors = append(result.Errors, "roll_number is already in use")
+			} else if first, ok := seenRollNumbers[roll]; ok {
+				result.Errors = append(result.Errors, fmt.Sprintf("roll_number duplicates row %d", first))
+			} else {
+				seenRollNumbers[roll] = line
+			}
+			user.RollNumber = &roll
+		}
+
+		if len(result.Errors) > 0 {
+			invalid++
+		}
+		users = append(users, user)
+		results = append(results, result)
+	}
+	return users, results, invalid, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package docs
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPageLoadsOnlyItsOwnCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/docs", Page)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	if external := regexp.MustCompile(`(?i)(src|href)="(https?:)?//`).FindString(w.Body.String()); external != "" {
+		t.Errorf("page references an external resource: %s", external)
+	}
+
+	csp := w.Header().Get("Content-Security-Policy")
+	for _, tag := range []string{"script", "style"} {
+		match := regexp.MustCompile(`(?s)<` + tag + `>(.*?)</` + tag + `>`).FindStringSubmatch(w.Body.String())
+		if match == nil {
+			t.Fatalf("page has no inline %s", tag)
+		}
+		sum := sha256.Sum256([]byte(match[1]))
+		if source := "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"; !strings.Contains(csp, tag+"-src "+source) {
+			t.Errorf("Content-Security-Policy %q does not allow the page's %s", csp, tag)
+		}
+	}
+	if !strings.Contains(csp, "default-src 'none'") {
+		t.Errorf("Content-Security-Policy %q does not deny by default", csp)
+	}
+}
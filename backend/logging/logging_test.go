@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSecretsAreRedacted(t *testing.T) {
+	secrets := []slog.Attr{
+		slog.String("password", "hunter2-password"),
+		slog.String("new_password", "hunter3-password"),
+		slog.String("token", "eyJ-jwt-token"),
+		slog.String("refresh_token", "refresh-token-value"),
+		slog.String("Authorization", "Bearer eyJ-bearer-token"),
+		slog.String("api_key", "gms_abc123_secret"),
+		slog.String("X-API-Key", "gms_def456_secret"),
+		slog.String("client_secret", "oidc-client-secret"),
+	}
+	secretArgs := func() []any {
+		args := make([]any, len(secrets))
+		for i, attr := range secrets {
+			args[i] = attr
+		}
+		return args
+	}
+
+	tests := []struct {
+		name string
+		log  func(logger *slog.Logger)
+	}{
+		{"arguments", func(logger *slog.Logger) {
+			logger.Info("signed in", secretArgs()...)
+		}},
+		{"logger attributes", func(logger *slog.Logger) {
+			logger.With(secretArgs()...).Info("signed in")
+		}},
+		{"context attributes", func(logger *slog.Logger) {
+			logger.InfoContext(WithAttrs(context.Background(), secrets...), "signed in")
+		}},
+		{"nested context attributes", func(logger *slog.Logger) {
+			ctx := WithAttrs(context.Background(), secrets[:4]...)
+			logger.InfoContext(WithAttrs(ctx, secrets[4:]...), "signed in")
+		}},
+		{"group", func(logger *slog.Logger) {
+			logger.Info("signed in", slog.Group("request", secretArgs()...))
+		}},
+		{"logger group", func(logger *slog.Logger) {
+			logger.WithGroup("request").InfoContext(WithAttrs(context.Background(), secrets...), "signed in")
+		}},
+	}
+
+	for _, format := range []string{FormatJSON, FormatText} {
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				var out bytes.Buffer
+				logger := New(&out, format, slog.LevelInfo)
+				tt.log(logger.With("user_id", 7))
+
+				line := out.String()
+				for _, attr := range secrets {
+					if strings.Contains(line, attr.Value.String()) {
+						t.Errorf("%s leaked: %s", attr.Key, line)
+					}
+				}
+				if got := strings.Count(line, Redacted); got != len(secrets) {
+					t.Errorf("%d values redacted, want %d: %s", got, len(secrets), line)
+				}
+				if !strings.Contains(line, "user_id") || !strings.Contains(line, "7") {
+					t.Errorf("other attributes were dropped: %s", line)
+				}
+			})
+		}
+	}
+}
@@ -1,45 +1,158 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"grade-management-system/config"
+	"grade-management-system/controllers"
+	"grade-management-system/logging"
+	"grade-management-system/metrics"
+	"grade-management-system/migrations"
 	"grade-management-system/models"
+	"grade-management-system/repository"
+	"grade-management-system/risk"
 	"grade-management-system/routes"
-	"log"
+	"grade-management-system/services"
+	"grade-management-system/tracing"
+	"grade-management-system/utils"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// gradeObserver counts saved grades and queues the students for their early
+// warnings to be refreshed
+type gradeObserver struct{}
+
+func (gradeObserver) GradesSaved(courseID uint, studentIDs []uint) {
+	metrics.GradesWritten(courseID, len(studentIDs))
+	risk.Enqueue(studentIDs...)
+}
+
+func (gradeObserver) ScoreSaved(courseID, studentID uint) {
+	risk.Enqueue(studentID)
+}
+
 func main() {
 	// Attempt to load .env file if it exists
 	_ = godotenv.Load()
+	logging.Setup()
+
+	// 1. Connect to Database, tracing its queries when tracing is enabled
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
 
-	// 1. Connect to Database
 	config.ConnectDatabase()
+	if tracing.Enabled() {
+		if err := config.DB.Use(tracing.GormPlugin{}); err != nil {
+			logging.Fatal("Failed to trace database queries", "error", err)
+		}
+	}
 
-	// 2. AutoMigrate Models
-	err := config.DB.AutoMigrate(
-		&models.User{},
-		&models.Course{},
-		&models.Enrollment{},
-		&models.Grade{},
-	)
+	// "main migrate <command>" manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrations.RunCommand(config.DB, os.Args[2:], os.Stdout); err != nil {
+			logging.Fatal("Migration failed", "error", err)
+		}
+		return
+	}
+
+	sqlDB, err := config.DB.DB()
 	if err != nil {
-		log.Fatal("Failed to auto-migrate database schema:", err)
+		logging.Fatal("Failed to access the database connection pool", "error", err)
+	}
+	if err := metrics.RegisterDB(sqlDB, "grade_management"); err != nil {
+		logging.Fatal("Failed to register database metrics", "error", err)
 	}
-	log.Println("Database schema migrated successfully")
 
-	// 3. Setup Routes
-	r := routes.SetupRoutes()
+	// 2. Make sure the schema is current. The server only changes it when
+	// MIGRATE_ON_START=true; otherwise run "migrate up" before deploying.
+	if os.Getenv("MIGRATE_ON_START") == "true" {
+		applied, err := migrations.Up(config.DB)
+		if err != nil {
+			logging.Fatal("Failed to migrate database schema", "error", err)
+		}
+		for _, migration := range applied {
+			slog.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+		}
+	} else if err := migrations.Check(config.DB); err != nil {
+		logging.Fatal(`Refusing to start. Run "migrate up" or set MIGRATE_ON_START=true.`, "error", err)
+	}
+
+	if err := models.EnsureDefaultRoles(config.DB); err != nil {
+		logging.Fatal("Failed to seed default roles", "error", err)
+	}
+	if err := models.EnsureDefaultRiskRules(config.DB); err != nil {
+		logging.Fatal("Failed to seed default risk rules", "error", err)
+	}
+	slog.Info("Database schema is up to date")
+
+	// 3. Load JWT signing keys so misconfiguration fails at startup, and
+	// reload them on SIGHUP so rotated keys need no restart
+	if err := utils.LoadSigningKeys(); err != nil {
+		logging.Fatal("Failed to load JWT signing keys", "error", err)
+	}
+	reloadKeys := make(chan os.Signal, 1)
+	signal.Notify(reloadKeys, syscall.SIGHUP)
+	go func() {
+		for range reloadKeys {
+			if err := utils.LoadSigningKeys(); err != nil {
+				slog.Error("Failed to reload JWT signing keys, keeping the current keys", "error", err)
+			}
+		}
+	}()
+
+	// 4. Keep early-warning flags current: changed students are re-evaluated
+	// in the background, and everyone on a schedule
+	risk.StartWorker(config.DB)
+	risk.StartScheduler(config.DB)
 
-	// 4. Start Server
+	// 5. Build the domain services on top of the database repositories
+	store := repository.NewGormStore(config.DB)
+	handlers := controllers.NewHandlers(
+		services.NewGradingService(store, gradeObserver{}),
+		services.NewEnrollmentService(store),
+		services.NewUserService(store),
+		services.NewCourseService(store),
+	)
+
+	// 6. Setup Routes
+	r := routes.SetupRoutes(handlers)
+
+	// 7. Start Server
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	log.Printf("Server is running on port %s", port)
-	if err := r.Run("0.0.0.0:" + port); err != nil {
-		log.Fatal("Failed to run server:", err)
+	server := &http.Server{Addr: "0.0.0.0:" + port, Handler: r}
+	go func() {
+		slog.Info("Server is running", "port", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("Failed to run server", "error", err)
+		}
+	}()
+
+	// 8. On SIGINT or SIGTERM, finish the requests in flight and flush the
+	// buffered spans before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	slog.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to shut down the server cleanly", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
 	}
 }
@@ -0,0 +1,90 @@
+// Package metrics holds the Prometheus collectors served on /metrics: HTTP
+// traffic, the database connection pool, logins and domain events.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every collector served by Handler
+var Registry = prometheus.NewRegistry()
+
+// Login methods and results, the labels of logins_total
+const (
+	LoginPassword = "password"
+	LoginSSO      = "sso"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to serve HTTP requests, by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "logins_total",
+		Help: "Login attempts, by method (password or sso) and result (success or failure).",
+	}, []string{"method", "result"})
+
+	gradesWritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grades_written_total",
+		Help: "Course grades created or updated, by course.",
+	}, []string{"course_id"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		logins,
+		gradesWritten,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RegisterDB exports the connection pool statistics of db as the go_sql_*
+// metrics, labelled with name
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveRequest records a served HTTP request. route is the route template,
+// such as /api/teacher/courses/:id, so paths with IDs share one series.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+	httpRequests.With(labels).Inc()
+	httpDuration.With(labels).Observe(duration.Seconds())
+}
+
+// LoginAttempt records a login with the given method
+func LoginAttempt(method string, success bool) {
+	result := "failure"
+	if success {
+		result = "success"
+	}
+	logins.WithLabelValues(method, result).Inc()
+}
+
+// GradesWritten records n grades created or updated in a course
+func GradesWritten(courseID uint, n int) {
+	gradesWritten.WithLabelValues(strconv.FormatUint(uint64(courseID), 10)).Add(float64(n))
+}
@@ -1,63 +1,244 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"grade-management-system/tracing"
 	"grade-management-system/utils"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// AuthRequired middleware ensures the user is authenticated via JWT
+// ChangePasswordPath is the only route open to users who must change a temporary password
+const ChangePasswordPath = "/api/me/password"
+
+// AuthRequired middleware ensures the request is authenticated, either by a
+// user JWT or by a service account API key (as a Bearer token or X-API-Key)
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			utils.ErrorResponse(c, http.StatusUnauthorized, "Authorization header is required")
+		// The auth span only covers authentication; the handlers that follow
+		// are children of the request span again
+		parent := trace.SpanFromContext(c.Request.Context())
+		ctx, span := tracing.Tracer().Start(c.Request.Context(), "auth")
+		c.Request = c.Request.WithContext(ctx)
+		claims, ok := authenticate(c)
+		span.SetAttributes(attribute.Bool("auth.authenticated", ok))
+		span.End()
+		c.Request = c.Request.WithContext(trace.ContextWithSpan(c.Request.Context(), parent))
+
+		if !ok {
 			c.Abort()
 			return
 		}
-
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid authorization header format")
-			c.Abort()
+		if claims != nil && claims.Actor != nil {
+			handleImpersonation(c, claims)
 			return
 		}
+		c.Next()
+	}
+}
 
-		tokenString := parts[1]
-		claims, err := utils.ValidateToken(tokenString)
-		if err != nil {
-			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token")
+// authenticate identifies the caller and stores who they are in the context,
+// or writes the error response. The claims are nil for API keys.
+func authenticate(c *gin.Context) (*utils.Claims, bool) {
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		return nil, authenticateAPIKey(c, apiKey)
+	}
+
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Authorization header is required")
+		return nil, false
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid authorization header format")
+		return nil, false
+	}
+
+	tokenString := parts[1]
+	if utils.IsAPIKey(tokenString) {
+		return nil, authenticateAPIKey(c, tokenString)
+	}
+
+	_, span := tracing.Tracer().Start(c.Request.Context(), "auth.validate_jwt")
+	claims, err := utils.ValidateToken(tokenString)
+	span.End()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token")
+		return nil, false
+	}
+
+	// Deactivation, deletion and role changes take effect immediately,
+	// so the account is checked on every request instead of trusting the token
+	user, ok := activeUser(c.Request.Context(), claims.UserID)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Account is deactivated or no longer exists")
+		return nil, false
+	}
+	if claims.Actor != nil {
+		if _, ok := activeUser(c.Request.Context(), claims.Actor.UserID); !ok {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Account is deactivated or no longer exists")
+			return nil, false
+		}
+	}
+
+	// Accounts with a temporary password may only change it
+	if user.MustChangePassword && claims.Actor == nil && c.FullPath() != ChangePasswordPath {
+		utils.ErrorResponse(c, http.StatusForbidden, "Password change required")
+		return nil, false
+	}
+
+	// Set variables to context for future use
+	c.Set("actorType", models.ActorUser)
+	c.Set("userID", user.ID)
+	c.Set("role", user.Role)
+	addLogAttrs(c, slog.Uint64("user_id", uint64(user.ID)), slog.String("role", user.Role))
+	return claims, true
+}
+
+// activeUser loads a user that has been neither deactivated nor deleted
+func activeUser(ctx context.Context, userID uint) (models.User, bool) {
+	var user models.User
+	if err := config.DB.WithContext(ctx).Select("id", "role", "deactivated_at", "must_change_password").First(&user, userID).Error; err != nil {
+		return user, false
+	}
+	return user, user.DeactivatedAt == nil
+}
+
+// handleImpersonation runs a request made with an impersonation token. The
+// target user's identity is already set; the admin is recorded as the real actor,
+// writes are refused for read-only sessions, and every request is audited.
+func handleImpersonation(c *gin.Context, claims *utils.Claims) {
+	c.Set("impersonatorID", claims.Actor.UserID)
+	addLogAttrs(c, slog.Uint64("impersonator_id", uint64(claims.Actor.UserID)))
+
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		if claims.ReadOnly {
+			utils.ErrorResponse(c, http.StatusForbidden, "Impersonated sessions are read-only")
 			c.Abort()
+			recordImpersonatedRequest(c, claims)
 			return
 		}
+	}
 
-		// Set variables to context for future use
-		c.Set("userID", claims.UserID)
-		c.Set("role", claims.Role)
-		c.Next()
+	c.Next()
+	recordImpersonatedRequest(c, claims)
+}
+
+func recordImpersonatedRequest(c *gin.Context, claims *utils.Claims) {
+	impersonatorID := claims.Actor.UserID
+	entry := models.AuditLog{
+		ActorType:      models.ActorUser,
+		ActorID:        claims.UserID,
+		ImpersonatorID: &impersonatorID,
+		Action:         "impersonation.request",
+		Resource:       "request",
+		Details:        fmt.Sprintf("%s %s -> %d (session %s)", c.Request.Method, c.Request.URL.RequestURI(), c.Writer.Status(), claims.ID),
+	}
+
+	if err := config.DB.WithContext(c.Request.Context()).Create(&entry).Error; err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to audit impersonated request", "error", err)
+	}
+}
+
+// authenticateAPIKey verifies an API key and records its use.
+// Requests made with an API key carry no user ID, so PermissionRequired turns
+// them away and they can only reach routes guarded by ScopeRequired.
+func authenticateAPIKey(c *gin.Context, key string) bool {
+	prefix, ok := utils.ParseAPIKeyPrefix(key)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid API key")
+		return false
+	}
+
+	var apiKey models.APIKey
+	if err := config.DB.WithContext(c.Request.Context()).Where("prefix = ?", prefix).First(&apiKey).Error; err != nil || !utils.CheckAPIKeyHash(key, apiKey.KeyHash) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid API key")
+		return false
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil || now.After(apiKey.ExpiresAt) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "API key has expired or been revoked")
+		return false
+	}
+
+	if err := config.DB.WithContext(c.Request.Context()).Model(&apiKey).UpdateColumn("last_used_at", now).Error; err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to record use of API key", "api_key_id", apiKey.ID, "error", err)
 	}
+
+	c.Set("actorType", models.ActorAPIKey)
+	c.Set("apiKeyID", apiKey.ID)
+	c.Set("serviceAccountID", apiKey.ServiceAccountID)
+	c.Set("scopes", strings.Fields(apiKey.Scopes))
+	c.Set("role", "service")
+	addLogAttrs(c, slog.Uint64("api_key_id", uint64(apiKey.ID)), slog.Uint64("service_account_id", uint64(apiKey.ServiceAccountID)), slog.String("role", "service"))
+	return true
 }
 
-// RoleRequired middleware ensures the authenticated user has one of the allowed roles
-func RoleRequired(allowedRoles ...string) gin.HandlerFunc {
+// ScopeRequired middleware ensures the request was made with an API key granted the scope
+func ScopeRequired(scope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
+		scopes, exists := c.Get("scopes")
 		if !exists {
-			utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized access")
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: an API key is required")
 			c.Abort()
 			return
 		}
 
-		userRole := role.(string)
-		for _, allowedRole := range allowedRoles {
-			if userRole == allowedRole {
+		for _, granted := range scopes.([]string) {
+			if granted == scope {
 				c.Next()
 				return
 			}
 		}
 
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: API key lacks scope "+scope)
+		c.Abort()
+	}
+}
+
+// PermissionRequired middleware ensures the authenticated user holds at least one
+// of the permissions through their roles. Permissions are read from the database
+// on each request, so role changes take effect immediately.
+func PermissionRequired(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			// API keys carry scopes instead of permissions
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: insufficient permissions")
+			c.Abort()
+			return
+		}
+
+		granted, err := models.UserPermissions(config.DB.WithContext(c.Request.Context()), userID.(uint), c.GetString("role"))
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to resolve permissions")
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			for _, name := range granted {
+				if name == permission {
+					c.Next()
+					return
+				}
+			}
+		}
+
 		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: insufficient permissions")
 		c.Abort()
 	}
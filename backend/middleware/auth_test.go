@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"encoding/json"
+	"grade-management-system/config"
+	"grade-management-system/migrations"
+	"grade-management-system/models"
+	"grade-management-system/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAuthRouter opens an in-memory database and serves AuthRequired in front
+// of a route for each guard under test
+func newAuthRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_EPHEMERAL_KEY", "true")
+
+	db, err := config.OpenSQLite(config.SQLiteMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.EnsureDefaultRoles(db); err != nil {
+		t.Fatal(err)
+	}
+	config.DB = db
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r := gin.New()
+	api := r.Group("/", AuthRequired())
+	api.GET("/grades", ScopeRequired(models.ScopeGradesRead), ok)
+	api.GET("/courses", PermissionRequired(models.PermCourseRead), ok)
+	api.Any("/whoami", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("userID"), "impersonator_id": c.GetUint("impersonatorID")})
+	})
+	return r
+}
+
+// serve sends a request with the given headers and returns the status
+func serve(r *gin.Engine, method, path string, headers map[string]string) int {
+	req := httptest.NewRequest(method, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAPIKeys(t *testing.T) {
+	r := newAuthRouter(t)
+
+	admin := models.User{Name: "Admin", Email: "admin@university.edu", Password: "unused", Role: models.RoleAdmin}
+	config.DB.Create(&admin)
+	account := models.ServiceAccount{Name: "SIS sync", CreatedByID: admin.ID}
+	config.DB.Create(&account)
+	adminToken, err := utils.GenerateToken(admin.ID, admin.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revokedAt := time.Now().Add(-time.Minute)
+	newKey := func(scopes string, expiresAt time.Time, revokedAt *time.Time) (string, models.APIKey) {
+		key, prefix, hash := utils.GenerateAPIKey()
+		apiKey := models.APIKey{ServiceAccountID: account.ID, Name: "key", Prefix: prefix, KeyHash: hash, Scopes: scopes, ExpiresAt: expiresAt, RevokedAt: revokedAt}
+		if err := config.DB.Create(&apiKey).Error; err != nil {
+			t.Fatal(err)
+		}
+		return key, apiKey
+	}
+	valid, validKey := newKey("courses:read grades:read", time.Now().Add(time.Hour), nil)
+	expired, _ := newKey("grades:read", time.Now().Add(-time.Minute), nil)
+	revoked, _ := newKey("grades:read", time.Now().Add(time.Hour), &revokedAt)
+	unscoped, _ := newKey("courses:read enrollments:read", time.Now().Add(time.Hour), nil)
+	prefix, _ := utils.ParseAPIKeyPrefix(valid)
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		want    int
+	}{
+		{"bearer key with the scope", "/grades", map[string]string{"Authorization": "Bearer " + valid}, http.StatusOK},
+		{"X-API-Key with the scope", "/grades", map[string]string{"X-API-Key": valid}, http.StatusOK},
+		{"expired key", "/grades", map[string]string{"Authorization": "Bearer " + expired}, http.StatusUnauthorized},
+		{"revoked key", "/grades", map[string]string{"X-API-Key": revoked}, http.StatusUnauthorized},
+		{"wrong secret", "/grades", map[string]string{"X-API-Key": utils.APIKeyPrefix + prefix + "_wrong"}, http.StatusUnauthorized},
+		{"unknown prefix", "/grades", map[string]string{"X-API-Key": utils.APIKeyPrefix + "000000_secret"}, http.StatusUnauthorized},
+		{"malformed key", "/grades", map[string]string{"X-API-Key": utils.APIKeyPrefix + prefix}, http.StatusUnauthorized},
+		{"key without the scope", "/grades", map[string]string{"Authorization": "Bearer " + unscoped}, http.StatusForbidden},
+		{"key on a permission route", "/courses", map[string]string{"Authorization": "Bearer " + valid}, http.StatusForbidden},
+		{"user on a scope route", "/grades", map[string]string{"Authorization": "Bearer " + adminToken}, http.StatusForbidden},
+		{"user on a permission route", "/courses", map[string]string{"Authorization": "Bearer " + adminToken}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(r, "GET", tt.path, tt.headers); got != tt.want {
+				t.Errorf("GET %s: got %d, want %d", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	var used models.APIKey
+	config.DB.First(&used, validKey.ID)
+	if used.LastUsedAt == nil {
+		t.Error("the key's use was not recorded")
+	}
+}
+
+func TestImpersonation(t *testing.T) {
+	r := newAuthRouter(t)
+
+	admin := models.User{Name: "Admin", Email: "admin@university.edu", Password: "unused", Role: models.RoleAdmin}
+	student := models.User{Name: "Student", Email: "student@university.edu", Password: "unused", Role: models.RoleStudent}
+	config.DB.Create(&admin)
+	config.DB.Create(&student)
+	token := func(readOnly bool) string {
+		token, _, err := utils.GenerateImpersonationToken(student.ID, student.Role, admin.ID, readOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	readOnly, writable := token(true), token(false)
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		want   int
+	}{
+		{"read-only GET", http.MethodGet, readOnly, http.StatusOK},
+		{"read-only HEAD", http.MethodHead, readOnly, http.StatusOK},
+		{"read-only OPTIONS", http.MethodOptions, readOnly, http.StatusOK},
+		{"read-only POST", http.MethodPost, readOnly, http.StatusForbidden},
+		{"read-only PUT", http.MethodPut, readOnly, http.StatusForbidden},
+		{"read-only PATCH", http.MethodPatch, readOnly, http.StatusForbidden},
+		{"read-only DELETE", http.MethodDelete, readOnly, http.StatusForbidden},
+		{"writable POST", http.MethodPost, writable, http.StatusOK},
+		{"writable DELETE", http.MethodDelete, writable, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.DB.Where("action = ?", "impersonation.request").Delete(&models.AuditLog{})
+
+			req := httptest.NewRequest(tt.method, "/whoami", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("%s /whoami: got %d, want %d", tt.method, w.Code, tt.want)
+			}
+			if tt.want == http.StatusOK && tt.method != http.MethodHead {
+				var who struct {
+					UserID         uint `json:"user_id"`
+					ImpersonatorID uint `json:"impersonator_id"`
+				}
+				json.Unmarshal(w.Body.Bytes(), &who)
+				if who.UserID != student.ID || who.ImpersonatorID != admin.ID {
+					t.Errorf("request ran as user %d for %d, want %d for %d", who.UserID, who.ImpersonatorID, student.ID, admin.ID)
+				}
+			}
+
+			// Refused requests are audited as well
+			var entry models.AuditLog
+			if err := config.DB.Where("action = ?", "impersonation.request").First(&entry).Error; err != nil {
+				t.Fatalf("request was not audited: %v", err)
+			}
+			if entry.ActorID != student.ID || entry.ImpersonatorID == nil || *entry.ImpersonatorID != admin.ID {
+				t.Errorf("audited as user %d impersonated by %v", entry.ActorID, entry.ImpersonatorID)
+			}
+		})
+	}
+
+	// The session ends as soon as either account is deactivated
+	for _, user := range []models.User{admin, student} {
+		config.DB.Model(&user).Update("deactivated_at", time.Now())
+		if got := serve(r, "GET", "/whoami", map[string]string{"Authorization": "Bearer " + readOnly}); got != http.StatusUnauthorized {
+			t.Errorf("with %s deactivated: got %d, want 401", user.Email, got)
+		}
+		config.DB.Model(&user).Update("deactivated_at", nil)
+	}
+}
@@ -0,0 +1,89 @@
+package migrations
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// Usage describes the migrate subcommand
+const Usage = `usage: migrate <command>
+
+  up          apply every pending migration
+  down [n]    revert the last n applied migrations (default 1)
+  to <v>      apply or revert migrations until version v is the latest applied (0 reverts all)
+  status      list the migrations and whether they are applied`
+
+// RunCommand runs the migrate subcommand with its arguments, reporting progress to out
+func RunCommand(db *gorm.DB, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", Usage)
+	}
+
+	var (
+		changed []Migration
+		err     error
+		verb    = "Applied"
+	)
+	switch args[0] {
+	case "up":
+		changed, err = Up(db)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("down takes a positive number of migrations, got %q", args[1])
+			}
+		}
+		changed, err = Down(db, steps)
+		verb = "Reverted"
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("to needs a version\n%s", Usage)
+		}
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		changed, err = To(db, version)
+		verb = "Migrated"
+	case "status":
+		return printStatus(db, out)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], Usage)
+	}
+
+	for _, migration := range changed {
+		fmt.Fprintf(out, "%s %04d_%s\n", verb, migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		fmt.Fprintln(out, "Nothing to do")
+	}
+
+	current, err := Current(db)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Schema is at version %d\n", current)
+	return nil
+}
+
+func printStatus(db *gorm.DB, out io.Writer) error {
+	statuses, err := StatusOf(db)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		state := "pending"
+		if status.AppliedAt != nil {
+			state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(out, "%04d_%-30s %s\n", status.Version, status.Name, state)
+	}
+	return nil
+}
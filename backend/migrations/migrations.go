@@ -0,0 +1,358 @@
+// Package migrations versions the database schema. Migrations are SQL files
+// embedded in the binary, one directory per database driver, named
+// NNNN_description.up.sql and NNNN_description.down.sql. Applied versions are
+// recorded in the schema_migrations table.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql
+var files embed.FS
+
+// Migration is one schema change and how to revert it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes a migration and whether it has been applied
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// appliedMigration is a row of the schema_migrations table
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+func (appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+const createVersionTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`
+
+// lockID identifies the Postgres advisory lock held while migrating
+const lockID = 7238513906
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// addColumnIfMissing matches the ALTER TABLE ... ADD COLUMN IF NOT EXISTS
+// statements that SQLite does not support, one per line
+var addColumnIfMissing = regexp.MustCompile(`(?m)^ALTER TABLE "(\w+)" ADD COLUMN IF NOT EXISTS "(\w+)" (.*);$`)
+
+// Load returns the migrations for the database's driver, ordered by version
+func Load(db *gorm.DB) ([]Migration, error) {
+	dir := path.Join("sql", db.Dialector.Name())
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database driver %s", db.Dialector.Name())
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest returns the version the embedded migrations bring the schema to
+func Latest(migrations []Migration) int64 {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// applied returns the applied versions and when they were applied. A database
+// without the schema_migrations table has none.
+func applied(db *gorm.DB) (map[int64]time.Time, error) {
+	versions := map[int64]time.Time{}
+	if !db.Migrator().HasTable(&appliedMigration{}) {
+		return versions, nil
+	}
+
+	var rows []appliedMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		versions[row.Version] = row.AppliedAt
+	}
+	return versions, nil
+}
+
+// Current returns the highest applied version, 0 for an empty database
+func Current(db *gorm.DB) (int64, error) {
+	versions, err := applied(db)
+	if err != nil {
+		return 0, err
+	}
+	var current int64
+	for version := range versions {
+		current = max(current, version)
+	}
+	return current, nil
+}
+
+// StatusOf lists every embedded migration with the time it was applied, if it was
+func StatusOf(db *gorm.DB) ([]Status, error) {
+	migrations, err := Load(db)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, migration := range migrations {
+		status := Status{Migration: migration}
+		if appliedAt, ok := versions[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration and returns the ones applied
+func Up(db *gorm.DB) ([]Migration, error) {
+	return To(db, -1)
+}
+
+// Down reverts the given number of most recently applied migrations
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := withLock(db, func(conn *gorm.DB) error {
+		migrations, versions, err := loadState(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			if _, ok := versions[migrations[i].Version]; !ok {
+				continue
+			}
+			if err := revert(conn, migrations[i]); err != nil {
+				return err
+			}
+			reverted = append(reverted, migrations[i])
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// To migrates up or down until exactly the migrations up to version are
+// applied, returning the migrations applied or reverted. A negative version
+// means the latest.
+func To(db *gorm.DB, version int64) ([]Migration, error) {
+	var changed []Migration
+	err := withLock(db, func(conn *gorm.DB) error {
+		migrations, versions, err := loadState(conn)
+		if err != nil {
+			return err
+		}
+		if version < 0 {
+			version = Latest(migrations)
+		} else if version != 0 && !known(migrations, version) {
+			return fmt.Errorf("unknown migration version %d", version)
+		}
+
+		// Revert newer migrations first, newest to oldest
+		for i := len(migrations) - 1; i >= 0; i-- {
+			if _, ok := versions[migrations[i].Version]; ok && migrations[i].Version > version {
+				if err := revert(conn, migrations[i]); err != nil {
+					return err
+				}
+				changed = append(changed, migrations[i])
+			}
+		}
+		for _, migration := range migrations {
+			if _, ok := versions[migration.Version]; !ok && migration.Version <= version {
+				if err := apply(conn, migration); err != nil {
+					return err
+				}
+				changed = append(changed, migration)
+			}
+		}
+		return nil
+	})
+	return changed, err
+}
+
+// ErrSchemaBehind is returned by Check when migrations are pending
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// Check verifies every embedded migration has been applied, without changing anything
+func Check(db *gorm.DB) error {
+	statuses, err := StatusOf(db)
+	if err != nil {
+		return err
+	}
+	var pending int
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d of %d migrations pending", ErrSchemaBehind, pending, len(statuses))
+	}
+	return nil
+}
+
+func known(migrations []Migration, version int64) bool {
+	for _, migration := range migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+func loadState(db *gorm.DB) ([]Migration, map[int64]time.Time, error) {
+	migrations, err := Load(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	versions, err := applied(db)
+	return migrations, versions, err
+}
+
+// apply runs a migration and records it in one transaction
+func apply(db *gorm.DB, migration Migration) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := run(tx, migration.Up); err != nil {
+			return err
+		}
+		return tx.Create(&appliedMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// revert runs a migration's down script and forgets it in one transaction
+func revert(db *gorm.DB, migration Migration) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := run(tx, migration.Down); err != nil {
+			return err
+		}
+		return tx.Where("version = ?", migration.Version).Delete(&appliedMigration{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("reverting migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// run executes a migration script. SQLite has no ADD COLUMN IF NOT EXISTS, so
+// there the script runs in parts and each such column is only added when the
+// table does not have it yet.
+func run(tx *gorm.DB, script string) error {
+	if tx.Dialector.Name() != "sqlite" {
+		return tx.Exec(script).Error
+	}
+
+	exec := func(part string) error {
+		if strings.TrimSpace(part) == "" {
+			return nil
+		}
+		return tx.Exec(part).Error
+	}
+	var done int
+	for _, match := range addColumnIfMissing.FindAllStringSubmatchIndex(script, -1) {
+		if err := exec(script[done:match[0]]); err != nil {
+			return err
+		}
+		done = match[1]
+
+		table, column, definition := script[match[2]:match[3]], script[match[4]:match[5]], script[match[6]:match[7]]
+		if tx.Migrator().HasColumn(table, column) {
+			continue
+		}
+		if err := tx.Exec(fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s`, table, column, definition)).Error; err != nil {
+			return err
+		}
+	}
+	return exec(script[done:])
+}
+
+// withLock creates the schema_migrations table if needed and runs fn on a
+// single connection holding the migration lock, so replicas starting at the
+// same time migrate one after the other. On Postgres this is a session
+// advisory lock; SQLite databases are not shared between replicas.
+func withLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	if db.Dialector.Name() != "postgres" {
+		if err := db.Exec(createVersionTable).Error; err != nil {
+			return err
+		}
+		return fn(db)
+	}
+
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockID).Error; err != nil {
+			return fmt.Errorf("acquiring migration lock: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockID)
+
+		if err := conn.Exec(createVersionTable).Error; err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
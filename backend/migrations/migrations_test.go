@@ -0,0 +1,137 @@
+package migrations
+
+import (
+	"grade-management-system/config"
+	"grade-management-system/models"
+	"testing"
+)
+
+// firstReleaseSchema is what AutoMigrate created for the first release's
+// models, plus audit_logs and grade_histories as they first shipped, before
+// any of the columns added to them later. SQLite support arrived after the
+// CHECK on users.role was dropped and courses.teacher_id became RESTRICT, so
+// only those fixes are left out.
+const firstReleaseSchema = `
+CREATE TABLE "users" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "name" text NOT NULL,
+    "email" text NOT NULL,
+    "password" text NOT NULL,
+    "role" text NOT NULL,
+    "created_at" datetime,
+    CONSTRAINT "uni_users_email" UNIQUE ("email")
+);
+CREATE TABLE "courses" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "name" text NOT NULL,
+    "teacher_id" integer NOT NULL,
+    CONSTRAINT "fk_courses_teacher" FOREIGN KEY ("teacher_id") REFERENCES "users"("id") ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT "uni_courses_name" UNIQUE ("name")
+);
+CREATE TABLE "enrollments" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "student_id" integer NOT NULL,
+    "course_id" integer NOT NULL,
+    CONSTRAINT "fk_enrollments_student" FOREIGN KEY ("student_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_enrollments_course" FOREIGN KEY ("course_id") REFERENCES "courses"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX "idx_student_course" ON "enrollments" ("student_id","course_id");
+CREATE TABLE "grades" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "student_id" integer NOT NULL,
+    "course_id" integer NOT NULL,
+    "marks" real NOT NULL,
+    "grade_letter" text NOT NULL,
+    CONSTRAINT "fk_grades_student" FOREIGN KEY ("student_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_grades_course" FOREIGN KEY ("course_id") REFERENCES "courses"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "chk_grades_marks" CHECK (marks >= 0 AND marks <= 100)
+);
+CREATE UNIQUE INDEX "idx_grading" ON "grades" ("student_id","course_id");
+CREATE TABLE "audit_logs" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "actor_type" text NOT NULL,
+    "actor_id" integer NOT NULL,
+    "service_account_id" integer,
+    "action" text NOT NULL,
+    "resource" text,
+    "resource_id" integer,
+    "details" text,
+    "created_at" datetime
+);
+CREATE TABLE "grade_histories" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "course_id" integer NOT NULL,
+    "student_id" integer NOT NULL,
+    "component_id" integer,
+    "old_value" real,
+    "new_value" real NOT NULL,
+    "actor_type" text NOT NULL,
+    "actor_id" integer NOT NULL,
+    "created_at" datetime
+);
+
+INSERT INTO "users" ("id", "name", "email", "password", "role", "created_at") VALUES
+    (1, 'Anjali Desai', 'anjali@university.edu', 'hash', 'teacher', CURRENT_TIMESTAMP),
+    (2, 'Ravi Kumar', 'ravi@university.edu', 'hash', 'student', CURRENT_TIMESTAMP);
+INSERT INTO "courses" ("id", "name", "teacher_id") VALUES (1, 'Algorithms', 1);
+INSERT INTO "enrollments" ("student_id", "course_id") VALUES (2, 1);
+INSERT INTO "grades" ("student_id", "course_id", "marks", "grade_letter") VALUES (2, 1, 85, 'B');
+`
+
+func TestUpAdoptsFirstReleaseSchema(t *testing.T) {
+	db, err := config.OpenSQLite(config.SQLiteMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(firstReleaseSchema).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(db); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, model := range []interface{}{&models.User{}, &models.Course{}, &models.AuditLog{}, &models.GradeHistory{}} {
+		if err := db.Find(model).Error; err != nil {
+			t.Errorf("reading %T after migrating: %v", model, err)
+		}
+	}
+	for _, index := range []string{"idx_users_deleted_at", "idx_users_roll_number"} {
+		if !db.Migrator().HasIndex(&models.User{}, index) {
+			t.Errorf("users has no index %s", index)
+		}
+	}
+
+	var course models.Course
+	if err := db.Preload("Teacher").First(&course, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if course.Teacher.Email != "anjali@university.edu" || course.CreatedAt.IsZero() || course.UpdatedAt.IsZero() {
+		t.Errorf("course after migrating: teacher %q, created %v, updated %v", course.Teacher.Email, course.CreatedAt, course.UpdatedAt)
+	}
+
+	var grade models.Grade
+	if err := db.Where("student_id = ? AND course_id = ?", 2, 1).First(&grade).Error; err != nil || grade.Marks != 85 {
+		t.Errorf("grade after migrating: %v, %v", grade.Marks, err)
+	}
+
+	// The new columns work with the old rows
+	roll := "CS-001"
+	if err := db.Model(&models.User{}).Where("id = ?", 2).Updates(map[string]interface{}{"roll_number": roll, "department": "CS"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.User{Name: "Copy", Email: "copy@university.edu", Password: "hash", Role: models.RoleStudent, RollNumber: &roll}).Error; err == nil {
+		t.Error("a duplicate roll number was accepted")
+	}
+	if err := db.Delete(&models.User{}, 2).Error; err != nil {
+		t.Fatal(err)
+	}
+	var students int64
+	db.Model(&models.User{}).Where("role = ?", models.RoleStudent).Count(&students)
+	if students != 0 {
+		t.Errorf("%d students visible after soft-deleting the only one", students)
+	}
+}
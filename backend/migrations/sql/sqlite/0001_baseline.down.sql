@@ -0,0 +1,20 @@
+-- Drops every table of the baseline, children first
+DROP TABLE IF EXISTS "risk_flags";
+DROP TABLE IF EXISTS "risk_rules";
+DROP TABLE IF EXISTS "attendance_records";
+DROP TABLE IF EXISTS "guardian_links";
+DROP TABLE IF EXISTS "grade_histories";
+DROP TABLE IF EXISTS "course_assistants";
+DROP TABLE IF EXISTS "component_scores";
+DROP TABLE IF EXISTS "assessment_components";
+DROP TABLE IF EXISTS "role_permissions";
+DROP TABLE IF EXISTS "permissions";
+DROP TABLE IF EXISTS "audit_logs";
+DROP TABLE IF EXISTS "api_keys";
+DROP TABLE IF EXISTS "service_accounts";
+DROP TABLE IF EXISTS "grades";
+DROP TABLE IF EXISTS "enrollments";
+DROP TABLE IF EXISTS "courses";
+DROP TABLE IF EXISTS "user_roles";
+DROP TABLE IF EXISTS "roles";
+DROP TABLE IF EXISTS "users";
@@ -0,0 +1,263 @@
+-- Baseline: the schema as the models defined it when versioned migrations were
+-- introduced. Tables and indexes are only created when missing, and the columns
+-- added to users, courses, audit_logs and grade_histories after their tables
+-- first shipped are added when missing, so databases set up by any release's
+-- AutoMigrate startup are adopted with their data.
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "name" text NOT NULL,
+    "email" text NOT NULL,
+    "password" text NOT NULL,
+    "role" text NOT NULL,
+    "created_at" datetime,
+    "roll_number" text,
+    "department" text,
+    "must_change_password" numeric NOT NULL DEFAULT false,
+    "deactivated_at" datetime,
+    "deleted_at" datetime,
+    "date_of_birth" datetime,
+    "guardian_revocable" numeric NOT NULL DEFAULT false,
+    CONSTRAINT "uni_users_email" UNIQUE ("email")
+);
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "roll_number" text;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "department" text;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "must_change_password" numeric NOT NULL DEFAULT false;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "deactivated_at" datetime;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "deleted_at" datetime;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "date_of_birth" datetime;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "guardian_revocable" numeric NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_roll_number" ON "users" ("roll_number");
+
+CREATE TABLE IF NOT EXISTS "roles" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "name" text NOT NULL,
+    "description" text,
+    "built_in" numeric NOT NULL DEFAULT false,
+    CONSTRAINT "uni_roles_name" UNIQUE ("name")
+);
+
+CREATE TABLE IF NOT EXISTS "user_roles" (
+    "user_id" integer,
+    "role_id" integer,
+    PRIMARY KEY ("user_id","role_id"),
+    CONSTRAINT "fk_user_roles_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_user_roles_role" FOREIGN KEY ("role_id") REFERENCES "roles"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "courses" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "name" text NOT NULL,
+    "description" text,
+    "term" text,
+    "department" text,
+    "teacher_id" integer NOT NULL,
+    "archived_at" datetime,
+    "hide_comparisons" numeric NOT NULL DEFAULT false,
+    "created_at" datetime,
+    "updated_at" datetime,
+    CONSTRAINT "fk_courses_teacher" FOREIGN KEY ("teacher_id") REFERENCES "users"("id") ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT "uni_courses_name" UNIQUE ("name")
+);
+ALTER TABLE "courses" ADD COLUMN IF NOT EXISTS "description" text;
+ALTER TABLE "courses" ADD COLUMN IF NOT EXISTS "term" text;
+ALTER TABLE "courses" ADD COLUMN IF NOT EXISTS "department" text;
+ALTER TABLE "courses" ADD COLUMN IF NOT EXISTS "archived_at" datetime;
+ALTER TABLE "courses" ADD COLUMN IF NOT EXISTS "hide_comparisons" numeric NOT NULL DEFAULT false;
+ALTER TABLE "courses" ADD COLUMN IF NOT EXISTS "created_at" datetime;
+ALTER TABLE "courses" ADD COLUMN IF NOT EXISTS "updated_at" datetime;
+UPDATE "courses" SET "created_at" = CURRENT_TIMESTAMP WHERE "created_at" IS NULL;
+UPDATE "courses" SET "updated_at" = "created_at" WHERE "updated_at" IS NULL;
+CREATE INDEX IF NOT EXISTS "idx_courses_department" ON "courses" ("department");
+CREATE INDEX IF NOT EXISTS "idx_courses_term" ON "courses" ("term");
+
+CREATE TABLE IF NOT EXISTS "enrollments" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "student_id" integer NOT NULL,
+    "course_id" integer NOT NULL,
+    CONSTRAINT "fk_enrollments_student" FOREIGN KEY ("student_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_enrollments_course" FOREIGN KEY ("course_id") REFERENCES "courses"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_student_course" ON "enrollments" ("student_id","course_id");
+
+CREATE TABLE IF NOT EXISTS "grades" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "student_id" integer NOT NULL,
+    "course_id" integer NOT NULL,
+    "marks" real NOT NULL,
+    "grade_letter" text NOT NULL,
+    CONSTRAINT "fk_grades_student" FOREIGN KEY ("student_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_grades_course" FOREIGN KEY ("course_id") REFERENCES "courses"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "chk_grades_marks" CHECK (marks >= 0 AND marks <= 100)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_grading" ON "grades" ("student_id","course_id");
+
+CREATE TABLE IF NOT EXISTS "service_accounts" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "name" text NOT NULL,
+    "description" text,
+    "created_by_id" integer NOT NULL,
+    "created_at" datetime,
+    CONSTRAINT "fk_service_accounts_created_by" FOREIGN KEY ("created_by_id") REFERENCES "users"("id") ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT "uni_service_accounts_name" UNIQUE ("name")
+);
+
+CREATE TABLE IF NOT EXISTS "api_keys" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "service_account_id" integer NOT NULL,
+    "name" text NOT NULL,
+    "prefix" text NOT NULL,
+    "key_hash" text NOT NULL,
+    "scopes" text NOT NULL,
+    "expires_at" datetime NOT NULL,
+    "last_used_at" datetime,
+    "revoked_at" datetime,
+    "created_at" datetime,
+    CONSTRAINT "fk_service_accounts_api_keys" FOREIGN KEY ("service_account_id") REFERENCES "service_accounts"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_keys_prefix" ON "api_keys" ("prefix");
+CREATE INDEX IF NOT EXISTS "idx_api_keys_service_account_id" ON "api_keys" ("service_account_id");
+
+CREATE TABLE IF NOT EXISTS "audit_logs" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "actor_type" text NOT NULL,
+    "actor_id" integer NOT NULL,
+    "service_account_id" integer,
+    "impersonator_id" integer,
+    "action" text NOT NULL,
+    "resource" text,
+    "resource_id" integer,
+    "details" text,
+    "created_at" datetime
+);
+ALTER TABLE "audit_logs" ADD COLUMN IF NOT EXISTS "impersonator_id" integer;
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_created_at" ON "audit_logs" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_action" ON "audit_logs" ("action");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_impersonator_id" ON "audit_logs" ("impersonator_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_actor" ON "audit_logs" ("actor_type","actor_id");
+
+CREATE TABLE IF NOT EXISTS "permissions" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "name" text NOT NULL,
+    "description" text,
+    CONSTRAINT "uni_permissions_name" UNIQUE ("name")
+);
+
+CREATE TABLE IF NOT EXISTS "role_permissions" (
+    "role_id" integer,
+    "permission_id" integer,
+    PRIMARY KEY ("role_id","permission_id"),
+    CONSTRAINT "fk_role_permissions_role" FOREIGN KEY ("role_id") REFERENCES "roles"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_role_permissions_permission" FOREIGN KEY ("permission_id") REFERENCES "permissions"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "assessment_components" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "course_id" integer NOT NULL,
+    "name" text NOT NULL,
+    "weight" real NOT NULL DEFAULT 0,
+    "max_score" real NOT NULL,
+    "created_at" datetime,
+    CONSTRAINT "fk_assessment_components_course" FOREIGN KEY ("course_id") REFERENCES "courses"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "chk_assessment_components_max_score" CHECK (max_score > 0),
+    CONSTRAINT "chk_assessment_components_weight" CHECK (weight >= 0 AND weight <= 100)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_course_component" ON "assessment_components" ("course_id","name");
+
+CREATE TABLE IF NOT EXISTS "component_scores" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "component_id" integer NOT NULL,
+    "student_id" integer NOT NULL,
+    "score" real NOT NULL,
+    "graded_by_id" integer NOT NULL,
+    "updated_at" datetime,
+    CONSTRAINT "fk_component_scores_component" FOREIGN KEY ("component_id") REFERENCES "assessment_components"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_component_scores_student" FOREIGN KEY ("student_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "chk_component_scores_score" CHECK (score >= 0)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_component_student" ON "component_scores" ("component_id","student_id");
+
+CREATE TABLE IF NOT EXISTS "course_assistants" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "course_id" integer NOT NULL,
+    "user_id" integer NOT NULL,
+    "can_view_roster" numeric NOT NULL DEFAULT false,
+    "can_enter_scores" numeric NOT NULL DEFAULT false,
+    "created_at" datetime,
+    CONSTRAINT "fk_course_assistants_course" FOREIGN KEY ("course_id") REFERENCES "courses"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_course_assistants_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_course_assistant" ON "course_assistants" ("course_id","user_id");
+
+CREATE TABLE IF NOT EXISTS "grade_histories" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "course_id" integer NOT NULL,
+    "student_id" integer NOT NULL,
+    "component_id" integer,
+    "old_value" real,
+    "new_value" real NOT NULL,
+    "actor_type" text NOT NULL,
+    "actor_id" integer NOT NULL,
+    "impersonator_id" integer,
+    "created_at" datetime
+);
+ALTER TABLE "grade_histories" ADD COLUMN IF NOT EXISTS "impersonator_id" integer;
+CREATE INDEX IF NOT EXISTS "idx_grade_histories_student_id" ON "grade_histories" ("student_id");
+CREATE INDEX IF NOT EXISTS "idx_grade_histories_course_id" ON "grade_histories" ("course_id");
+
+CREATE TABLE IF NOT EXISTS "guardian_links" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "guardian_id" integer NOT NULL,
+    "student_id" integer NOT NULL,
+    "status" text NOT NULL,
+    "created_by_id" integer NOT NULL,
+    "approved_at" datetime,
+    "revoked_at" datetime,
+    "created_at" datetime,
+    CONSTRAINT "fk_guardian_links_guardian" FOREIGN KEY ("guardian_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_guardian_links_student" FOREIGN KEY ("student_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "chk_guardian_links_status" CHECK (status IN ('pending', 'active', 'revoked'))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_guardian_student" ON "guardian_links" ("guardian_id","student_id");
+
+CREATE TABLE IF NOT EXISTS "attendance_records" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "course_id" integer NOT NULL,
+    "student_id" integer NOT NULL,
+    "date" date NOT NULL,
+    "status" text NOT NULL,
+    "recorded_by_id" integer NOT NULL,
+    "updated_at" datetime,
+    CONSTRAINT "fk_attendance_records_course" FOREIGN KEY ("course_id") REFERENCES "courses"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_attendance_records_student" FOREIGN KEY ("student_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "chk_attendance_records_status" CHECK (status IN ('present', 'absent', 'late', 'excused'))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_attendance" ON "attendance_records" ("course_id","student_id","date");
+
+CREATE TABLE IF NOT EXISTS "risk_rules" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "key" text NOT NULL,
+    "description" text,
+    "enabled" numeric NOT NULL DEFAULT true,
+    "threshold" real NOT NULL DEFAULT 0,
+    "min_count" integer NOT NULL DEFAULT 0,
+    "updated_at" datetime,
+    CONSTRAINT "uni_risk_rules_key" UNIQUE ("key")
+);
+
+CREATE TABLE IF NOT EXISTS "risk_flags" (
+    "id" integer PRIMARY KEY AUTOINCREMENT,
+    "student_id" integer NOT NULL,
+    "course_id" integer,
+    "rule_key" text NOT NULL,
+    "reason" text NOT NULL,
+    "raised_at" datetime NOT NULL,
+    "updated_at" datetime,
+    "resolved_at" datetime,
+    CONSTRAINT "fk_risk_flags_student" FOREIGN KEY ("student_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_risk_flags_course" FOREIGN KEY ("course_id") REFERENCES "courses"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_risk_flags_resolved_at" ON "risk_flags" ("resolved_at");
+CREATE INDEX IF NOT EXISTS "idx_risk_flags_course_id" ON "risk_flags" ("course_id");
+CREATE INDEX IF NOT EXISTS "idx_risk_flags_student_id" ON "risk_flags" ("student_id");
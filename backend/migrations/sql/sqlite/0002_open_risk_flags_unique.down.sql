@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "idx_risk_flags_open";
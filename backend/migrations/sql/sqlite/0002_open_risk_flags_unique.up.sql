@@ -0,0 +1,10 @@
+-- A student holds at most one open flag per rule and course, so concurrent
+-- evaluations cannot raise the same flag twice. Duplicates raised before this
+-- index existed are resolved, keeping the oldest.
+UPDATE "risk_flags" SET "resolved_at" = CURRENT_TIMESTAMP
+WHERE "resolved_at" IS NULL AND "id" NOT IN (
+    SELECT MIN("id") FROM "risk_flags" WHERE "resolved_at" IS NULL GROUP BY "student_id", "course_id", "rule_key"
+);
+
+-- Term-wide flags have no course; COALESCE makes their NULLs compare equal
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_risk_flags_open" ON "risk_flags" ("student_id", COALESCE("course_id", 0), "rule_key") WHERE "resolved_at" IS NULL;
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// AssessmentComponent is a graded part of a course such as a quiz, lab or midterm
+type AssessmentComponent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CourseID  uint      `gorm:"uniqueIndex:idx_course_component;not null" json:"course_id"`
+	Name      string    `gorm:"uniqueIndex:idx_course_component;not null" json:"name"`
+	Weight    float64   `gorm:"not null;default:0;check:weight >= 0 AND weight <= 100" json:"weight"`
+	MaxScore  float64   `gorm:"not null;check:max_score > 0" json:"max_score"`
+	CreatedAt time.Time `json:"created_at"`
+	// Relationship
+	Course Course `gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+// ComponentScore is a student's score in one assessment component.
+// Includes unique index so a student only has one score per component.
+type ComponentScore struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ComponentID uint      `gorm:"uniqueIndex:idx_component_student;not null" json:"component_id"`
+	StudentID   uint      `gorm:"uniqueIndex:idx_component_student;not null" json:"student_id"`
+	Score       float64   `gorm:"not null;check:score >= 0" json:"score"`
+	GradedByID  uint      `gorm:"not null" json:"graded_by_id"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// Relationships
+	Component AssessmentComponent `gorm:"foreignKey:ComponentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Student   User                `gorm:"foreignKey:StudentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Attendance statuses. Late counts as attended.
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceLate    = "late"
+	AttendanceExcused = "excused"
+)
+
+// AttendanceRecord is a student's attendance in one course session.
+// Includes unique index so there is one record per student, course and day.
+type AttendanceRecord struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	CourseID     uint      `gorm:"uniqueIndex:idx_attendance;not null" json:"course_id"`
+	StudentID    uint      `gorm:"uniqueIndex:idx_attendance;not null" json:"student_id"`
+	Date         time.Time `gorm:"uniqueIndex:idx_attendance;type:date;not null" json:"date"`
+	Status       string    `gorm:"not null;check:status IN ('present', 'absent', 'late', 'excused')" json:"status"`
+	RecordedByID uint      `gorm:"not null" json:"recorded_by_id"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	// Relationships
+	Course  Course `gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Student User   `gorm:"foreignKey:StudentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Actor types recorded in the audit log
+const (
+	ActorUser   = "user"
+	ActorAPIKey = "api_key"
+)
+
+// AuditLog records who changed what, whether a user or an API key acting for a
+// service account. During impersonation ActorID is the impersonated user and
+// ImpersonatorID the admin really making the request.
+type AuditLog struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	ActorType        string    `gorm:"not null;size:20;index:idx_audit_actor" json:"actor_type"`
+	ActorID          uint      `gorm:"not null;index:idx_audit_actor" json:"actor_id"` // User ID or API key ID
+	ServiceAccountID *uint     `json:"service_account_id,omitempty"`
+	ImpersonatorID   *uint     `gorm:"index" json:"impersonator_id,omitempty"`
+	Action           string    `gorm:"not null;index" json:"action"`
+	Resource         string    `json:"resource"`
+	ResourceID       uint      `json:"resource_id"`
+	Details          string    `json:"details"`
+	CreatedAt        time.Time `gorm:"index" json:"created_at"`
+}
@@ -1,10 +1,62 @@
 package models
 
-// Course represents a class taught by a Teacher
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Course represents a class taught by a Teacher.
+// Archived courses accept no new enrollments but keep their grades.
 type Course struct {
-	ID        uint   `gorm:"primaryKey" json:"id"`
-	Name      string `gorm:"not null;unique" json:"name"`
-	TeacherID uint   `gorm:"not null" json:"teacher_id"`
-	// Relationship
-	Teacher   User   `gorm:"foreignKey:TeacherID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"teacher,omitempty"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Name        string     `gorm:"not null;unique" json:"name"`
+	Description string     `json:"description"`
+	Term        string     `gorm:"size:50;index" json:"term"`
+	Department  string     `gorm:"size:100;index" json:"department"`
+	TeacherID   uint       `gorm:"not null" json:"teacher_id"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+	// Set by the teacher to keep class comparisons from students and guardians
+	HideComparisons bool      `gorm:"not null;default:false" json:"hide_comparisons"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	// Relationship. A teacher who still owns courses cannot be removed, so the
+	// course is never left without one.
+	Teacher User `gorm:"foreignKey:TeacherID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"teacher,omitempty"`
+}
+
+var termYear = regexp.MustCompile(`\b(\d{4})\b`)
+
+// termSeasons orders the seasons of a year. Autumn is an alias of fall.
+var termSeasons = []string{"winter", "spring", "summer", "fall", "autumn"}
+
+// termKey is the year and season named in a term such as 2025-Fall or
+// Spring 2026, zero for whichever part the name lacks
+func termKey(term string) (year, season int) {
+	if match := termYear.FindStringSubmatch(term); match != nil {
+		year, _ = strconv.Atoi(match[1])
+	}
+	lower := strings.ToLower(term)
+	for i, name := range termSeasons {
+		if strings.Contains(lower, name) {
+			season = min(i, 3) + 1
+			break
+		}
+	}
+	return year, season
+}
+
+// CompareTerms orders terms chronologically by year and then season, so
+// 2025-Fall comes before 2026-Spring. Terms that name neither compare by name.
+func CompareTerms(a, b string) int {
+	yearA, seasonA := termKey(a)
+	yearB, seasonB := termKey(b)
+	if yearA != yearB {
+		return yearA - yearB
+	}
+	if seasonA != seasonB {
+		return seasonA - seasonB
+	}
+	return strings.Compare(a, b)
 }
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// CourseAssistant delegates specific abilities in a course to a teaching assistant.
+// Assistants never publish or change final grades; that stays with the course teacher.
+type CourseAssistant struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	CourseID       uint      `gorm:"uniqueIndex:idx_course_assistant;not null" json:"course_id"`
+	UserID         uint      `gorm:"uniqueIndex:idx_course_assistant;not null" json:"user_id"`
+	CanViewRoster  bool      `gorm:"not null;default:false" json:"can_view_roster"`
+	CanEnterScores bool      `gorm:"not null;default:false" json:"can_enter_scores"`
+	CreatedAt      time.Time `json:"created_at"`
+	// Relationships
+	Course Course `gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"course,omitempty"`
+	User   User   `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"user,omitempty"`
+}
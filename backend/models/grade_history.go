@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// GradeHistory records every change to a final grade or component score and who made it.
+// ComponentID is nil for changes to the final course grade.
+type GradeHistory struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	CourseID       uint      `gorm:"not null;index" json:"course_id"`
+	StudentID      uint      `gorm:"not null;index" json:"student_id"`
+	ComponentID    *uint     `json:"component_id"`
+	OldValue       *float64  `json:"old_value"`
+	NewValue       float64   `gorm:"not null" json:"new_value"`
+	ActorType      string    `gorm:"not null;size:20" json:"actor_type"`
+	ActorID        uint      `gorm:"not null" json:"actor_id"`  // User ID or API key ID
+	ImpersonatorID *uint     `json:"impersonator_id,omitempty"` // Admin who made the change while impersonating the actor
+	CreatedAt      time.Time `json:"created_at"`
+}
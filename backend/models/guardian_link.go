@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// Guardian link states
+const (
+	GuardianLinkPending = "pending"
+	GuardianLinkActive  = "active"
+	GuardianLinkRevoked = "revoked"
+)
+
+// GuardianLink gives a guardian read-only access to a student's records once active.
+// Links created by an admin are active immediately; invitations from a guardian
+// stay pending until the student approves them.
+type GuardianLink struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	GuardianID  uint       `gorm:"uniqueIndex:idx_guardian_student;not null" json:"guardian_id"`
+	StudentID   uint       `gorm:"uniqueIndex:idx_guardian_student;not null" json:"student_id"`
+	Status      string     `gorm:"not null;check:status IN ('pending', 'active', 'revoked')" json:"status"`
+	CreatedByID uint       `gorm:"not null" json:"created_by_id"`
+	ApprovedAt  *time.Time `json:"approved_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// Relationships
+	Guardian User `gorm:"foreignKey:GuardianID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"guardian,omitempty"`
+	Student  User `gorm:"foreignKey:StudentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"student,omitempty"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Early-warning rule keys
+const (
+	RiskRuleFailingComponents  = "failing_components"
+	RiskRuleLowAttendance      = "low_attendance"
+	RiskRuleGPADrop            = "gpa_drop"
+	RiskRuleMissingAssessments = "missing_assessments"
+)
+
+// RiskRule is an admin-configurable early-warning rule. What Threshold and
+// MinCount mean depends on the rule, see DefaultRiskRules.
+type RiskRule struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Key         string    `gorm:"not null;unique" json:"key"`
+	Description string    `json:"description"`
+	Enabled     bool      `gorm:"not null;default:true" json:"enabled"`
+	Threshold   float64   `gorm:"not null;default:0" json:"threshold"`
+	MinCount    int       `gorm:"not null;default:0" json:"min_count"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DefaultRiskRules are created on startup when missing. Existing rules keep
+// the thresholds an admin has set.
+var DefaultRiskRules = []RiskRule{
+	{
+		Key:         RiskRuleFailingComponents,
+		Description: "At least min_count assessment components scored below threshold percent",
+		Enabled:     true, Threshold: 60, MinCount: 1,
+	},
+	{
+		Key:         RiskRuleLowAttendance,
+		Description: "Attendance rate below threshold percent once at least min_count sessions are recorded",
+		Enabled:     true, Threshold: 75, MinCount: 3,
+	},
+	{
+		Key:         RiskRuleGPADrop,
+		Description: "Term GPA fell by at least threshold points from the previous term",
+		Enabled:     true, Threshold: 0.5,
+	},
+	{
+		Key:         RiskRuleMissingAssessments,
+		Description: "At least min_count assessment components other students were scored in have no score",
+		Enabled:     true, MinCount: 2,
+	},
+}
+
+// RiskFlag records that a student currently matches a rule, in a course or,
+// for term-wide rules, across courses. Flags are resolved rather than deleted
+// once the student no longer matches.
+type RiskFlag struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	StudentID  uint       `gorm:"index;not null" json:"student_id"`
+	CourseID   *uint      `gorm:"index" json:"course_id"`
+	RuleKey    string     `gorm:"not null" json:"rule"`
+	Reason     string     `gorm:"not null" json:"reason"`
+	RaisedAt   time.Time  `gorm:"not null" json:"raised_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ResolvedAt *time.Time `gorm:"index" json:"resolved_at,omitempty"`
+	// Relationships
+	Student User    `gorm:"foreignKey:StudentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Course  *Course `gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+// EnsureDefaultRiskRules creates the built-in early-warning rules that do not
+// exist yet. It is safe to run on every start.
+func EnsureDefaultRiskRules(db *gorm.DB) error {
+	for _, rule := range DefaultRiskRules {
+		if err := db.Where("key = ?", rule.Key).FirstOrCreate(&rule).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
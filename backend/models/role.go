@@ -0,0 +1,175 @@
+package models
+
+import (
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// Permission names, written as resource.action
+const (
+	PermUserCreate           = "user.create"
+	PermUserManage           = "user.manage"
+	PermStudentRead          = "student.read"
+	PermCourseCreate         = "course.create"
+	PermCourseRead           = "course.read"
+	PermCourseManage         = "course.manage"
+	PermEnrollmentManage     = "enrollment.manage"
+	PermGradeExport          = "grade.export"
+	PermRoleManage           = "role.manage"
+	PermServiceAccountManage = "service_account.manage"
+	PermAuditRead            = "audit.read"
+	PermCourseTeach          = "course.teach"
+	PermEnrollmentWrite      = "enrollment.write"
+	PermGradeWrite           = "grade.write"
+	PermGradeStatsRead       = "grade.stats.read"
+	PermCourseAssist         = "course.assist"
+	PermOwnCoursesRead       = "own.courses.read"
+	PermOwnGradesRead        = "own.grades.read"
+	PermOwnAttendanceRead    = "own.attendance.read"
+	PermAttendanceWrite      = "attendance.write"
+	PermGuardianManage       = "guardian.manage"
+	PermGuardianRead         = "guardian.read"
+	PermUserImpersonate      = "user.impersonate"
+	PermRiskRead             = "risk.read"
+	PermRiskManage           = "risk.manage"
+	PermAnalyticsRead        = "analytics.read"
+)
+
+// PermissionDescriptions documents every permission known to the system
+var PermissionDescriptions = map[string]string{
+	PermUserCreate:           "Create teacher and student accounts",
+	PermUserManage:           "Edit, deactivate, reactivate and delete user accounts",
+	PermStudentRead:          "List all students",
+	PermCourseCreate:         "Create courses and assign teachers",
+	PermCourseRead:           "List all courses",
+	PermCourseManage:         "Edit, reassign, archive and delete courses",
+	PermEnrollmentManage:     "Enroll students in any course",
+	PermGradeExport:          "Export grades across all courses and any student's record",
+	PermRoleManage:           "Manage roles and role assignments",
+	PermServiceAccountManage: "Manage service accounts and API keys",
+	PermAuditRead:            "Read the audit log",
+	PermCourseTeach:          "View the courses assigned to you as a teacher",
+	PermEnrollmentWrite:      "Enroll students in courses you teach",
+	PermGradeWrite:           "Add or update grades in courses you teach",
+	PermGradeStatsRead:       "View grade statistics for courses you teach",
+	PermCourseAssist:         "Act as a teaching assistant in courses delegated to you",
+	PermOwnCoursesRead:       "View the courses you are enrolled in",
+	PermOwnGradesRead:        "View your own grades and GPA",
+	PermOwnAttendanceRead:    "View your own attendance",
+	PermAttendanceWrite:      "Record attendance in courses you teach",
+	PermGuardianManage:       "Link guardians to students and manage guardian settings",
+	PermGuardianRead:         "View the courses, grades, GPA and attendance of linked students",
+	PermUserImpersonate:      "View the system as another non-admin user",
+	PermRiskRead:             "View at-risk students across all courses",
+	PermRiskManage:           "Configure and run the early-warning rules",
+	PermAnalyticsRead:        "View institution-wide enrollment and grade analytics",
+}
+
+// Names of the built-in roles that preserve the original admin/teacher/student behavior
+const (
+	RoleAdmin   = "admin"
+	RoleTeacher = "teacher"
+	RoleStudent = "student"
+	// RoleGuardian has read-only access to the students linked to them
+	RoleGuardian = "guardian"
+	// RoleTeachingAssistant is granted automatically while a user assists in a course
+	RoleTeachingAssistant = "teaching_assistant"
+)
+
+// DefaultRolePermissions lists the permissions of each built-in role
+var DefaultRolePermissions = map[string][]string{
+	RoleAdmin: {
+		PermUserCreate, PermStudentRead, PermCourseCreate, PermCourseRead,
+		PermRoleManage, PermServiceAccountManage, PermAuditRead, PermGuardianManage,
+		PermUserImpersonate, PermUserManage, PermCourseManage, PermGradeExport,
+		PermEnrollmentManage, PermRiskRead, PermRiskManage,
+		PermAnalyticsRead,
+	},
+	RoleTeacher: {
+		PermCourseTeach, PermEnrollmentWrite, PermGradeWrite, PermGradeStatsRead, PermAttendanceWrite,
+	},
+	RoleStudent: {
+		PermOwnCoursesRead, PermOwnGradesRead, PermOwnAttendanceRead,
+	},
+	RoleGuardian: {
+		PermGuardianRead,
+	},
+	RoleTeachingAssistant: {
+		PermCourseAssist,
+	},
+}
+
+// Permission is a single capability that can be granted through roles
+type Permission struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"not null;unique" json:"name"`
+	Description string `json:"description"`
+}
+
+// Role is a named set of permissions. Built-in roles cannot be edited or deleted.
+type Role struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"not null;unique" json:"name"`
+	Description string       `json:"description"`
+	BuiltIn     bool         `gorm:"not null;default:false" json:"built_in"`
+	Permissions []Permission `gorm:"many2many:role_permissions;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"permissions,omitempty"`
+}
+
+// EnsureDefaultRoles creates every known permission and the built-in roles,
+// adding any permission a built-in role is missing. It is safe to run on every start.
+func EnsureDefaultRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		permissions := make(map[string]Permission)
+		for name, description := range PermissionDescriptions {
+			permission := Permission{Name: name, Description: description}
+			if err := tx.Where("name = ?", name).FirstOrCreate(&permission).Error; err != nil {
+				return err
+			}
+			permissions[name] = permission
+		}
+
+		for roleName, names := range DefaultRolePermissions {
+			role := Role{Name: roleName, Description: "Built-in " + roleName + " role", BuiltIn: true}
+			if err := tx.Where("name = ?", roleName).FirstOrCreate(&role).Error; err != nil {
+				return err
+			}
+
+			var granted []Permission
+			for _, name := range names {
+				granted = append(granted, permissions[name])
+			}
+			// Append only adds missing rows to role_permissions
+			if err := tx.Model(&role).Association("Permissions").Append(granted); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// IsAdminPermission reports whether a permission is granted by the built-in
+// admin role and no other built-in role, i.e. whether holding it gives a user
+// admin-level powers
+func IsAdminPermission(name string) bool {
+	for role, permissions := range DefaultRolePermissions {
+		if role != RoleAdmin && slices.Contains(permissions, name) {
+			return false
+		}
+	}
+	return slices.Contains(DefaultRolePermissions[RoleAdmin], name)
+}
+
+// UserPermissions returns the names of the permissions granted to a user through
+// their primary role and any additional roles assigned to them
+func UserPermissions(db *gorm.DB, userID uint, primaryRole string) ([]string, error) {
+	var names []string
+	err := db.Model(&Permission{}).
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name = ? OR roles.id IN (?)", primaryRole,
+			db.Table("user_roles").Select("role_id").Where("user_id = ?", userID)).
+		Pluck("permissions.name", &names).Error
+	return names, err
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// Scopes that can be granted to an API key
+const (
+	ScopeCoursesRead      = "courses:read"
+	ScopeEnrollmentsRead  = "enrollments:read"
+	ScopeEnrollmentsWrite = "enrollments:write"
+	ScopeGradesRead       = "grades:read"
+	ScopeGradesWrite      = "grades:write"
+)
+
+// ValidScopes lists every scope an admin may grant
+var ValidScopes = []string{
+	ScopeCoursesRead,
+	ScopeEnrollmentsRead,
+	ScopeEnrollmentsWrite,
+	ScopeGradesRead,
+	ScopeGradesWrite,
+}
+
+// ServiceAccount is a non-human identity used by integrations such as LMS sync scripts
+type ServiceAccount struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"not null;unique" json:"name"`
+	Description string    `json:"description"`
+	CreatedByID uint      `gorm:"not null" json:"created_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Relationships
+	CreatedBy User     `gorm:"foreignKey:CreatedByID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"-"`
+	APIKeys   []APIKey `json:"api_keys,omitempty"`
+}
+
+// APIKey authenticates a service account. Only a SHA-256 hash of the key is
+// stored; the prefix is kept in clear text to look the key up and identify it.
+type APIKey struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	ServiceAccountID uint       `gorm:"not null;index" json:"service_account_id"`
+	Name             string     `gorm:"not null" json:"name"`
+	Prefix           string     `gorm:"not null;uniqueIndex;size:16" json:"prefix"`
+	KeyHash          string     `gorm:"not null" json:"-"`      // Never returned in JSON
+	Scopes           string     `gorm:"not null" json:"scopes"` // Space separated
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	// Relationship
+	ServiceAccount ServiceAccount `gorm:"foreignKey:ServiceAccountID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
@@ -2,14 +2,32 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
-// User represents Admin, Teacher, or Student in the system
+// User represents Admin, Teacher, or Student in the system.
+// Role is the primary role; Roles holds any additional roles granted by an admin.
+// Deactivated users cannot log in; deleted users are soft-deleted so their
+// historical grades keep pointing at them.
 type User struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	Name      string    `gorm:"not null" json:"name"`
 	Email     string    `gorm:"unique;not null" json:"email"`
 	Password  string    `gorm:"not null" json:"-"` // Don't return password in JSON
-	Role      string    `gorm:"not null;check:role IN ('admin', 'teacher', 'student')" json:"role"`
+	Role      string    `gorm:"not null" json:"role"`
 	CreatedAt time.Time `json:"created_at"`
+	// Optional institutional details, set when users are imported
+	RollNumber *string `gorm:"size:50;uniqueIndex" json:"roll_number,omitempty"`
+	Department string  `gorm:"size:100" json:"department,omitempty"`
+	// Set for accounts created with a generated temporary password
+	MustChangePassword bool `gorm:"not null;default:false" json:"must_change_password"`
+	// Account status
+	DeactivatedAt *time.Time     `json:"deactivated_at,omitempty"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	// Guardian visibility settings, only meaningful for students
+	DateOfBirth       *time.Time `json:"date_of_birth,omitempty"`
+	GuardianRevocable bool       `gorm:"not null;default:false" json:"guardian_revocable"`
+	// Relationship
+	Roles []Role `gorm:"many2many:user_roles;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"roles,omitempty"`
 }
@@ -0,0 +1,393 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grade-management-system/models"
+	"math"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// NewGormStore returns a Store backed by the given database connection
+func NewGormStore(db *gorm.DB) Store {
+	return gormStore{db: db}
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+func (s gormStore) Users() UserRepository             { return gormUsers{s.db} }
+func (s gormStore) Courses() CourseRepository         { return gormCourses{s.db} }
+func (s gormStore) Enrollments() EnrollmentRepository { return gormEnrollments{s.db} }
+func (s gormStore) Grades() GradeRepository           { return gormGrades{s.db} }
+
+func (s gormStore) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(gormStore{db: tx})
+	})
+}
+
+// notFound translates GORM's missing-record error into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// duplicate translates the driver's unique violation error into ErrDuplicate
+func duplicate(db *gorm.DB, err error) error {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+type gormUsers struct {
+	db *gorm.DB
+}
+
+func (r gormUsers) FindByID(ctx context.Context, id uint) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).First(&user, id).Error
+	return user, notFound(err)
+}
+
+func (r gormUsers) FindByIDsOrEmails(ctx context.Context, ids []uint, emails []string) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Where("id IN ? OR email IN ?", ids, emails).Find(&users).Error
+	return users, err
+}
+
+func (r gormUsers) ActiveStudentIDs(ctx context.Context, department string, courseID uint) ([]uint, error) {
+	db := r.db.WithContext(ctx)
+	query := db.Model(&models.User{}).Where("role = ? AND deactivated_at IS NULL", models.RoleStudent)
+	if department != "" {
+		query = query.Where("department = ?", department)
+	}
+	if courseID != 0 {
+		query = query.Where("id IN (?)", db.Model(&models.Enrollment{}).Select("student_id").Where("course_id = ?", courseID))
+	}
+
+	var ids []uint
+	err := query.Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r gormUsers) EmailInUse(ctx context.Context, email string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.User{}).Where("email = ? AND id <> ?", email, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r gormUsers) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r gormUsers) Update(ctx context.Context, user *models.User, fields ...string) error {
+	return r.db.WithContext(ctx).Model(user).Select(fields).Updates(user).Error
+}
+
+func (r gormUsers) Delete(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Delete(user).Error
+}
+
+type gormCourses struct {
+	db *gorm.DB
+}
+
+func (r gormCourses) FindByID(ctx context.Context, id uint) (models.Course, error) {
+	var course models.Course
+	err := r.db.WithContext(ctx).First(&course, id).Error
+	return course, notFound(err)
+}
+
+func (r gormCourses) Create(ctx context.Context, course *models.Course) error {
+	return r.db.WithContext(ctx).Create(course).Error
+}
+
+func (r gormCourses) Update(ctx context.Context, course *models.Course, fields ...string) error {
+	return r.db.WithContext(ctx).Model(course).Select(fields).Updates(course).Error
+}
+
+func (r gormCourses) Delete(ctx context.Context, course *models.Course) error {
+	return r.db.WithContext(ctx).Delete(course).Error
+}
+
+func (r gormCourses) CountByTeacher(ctx context.Context, teacherID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Course{}).Where("teacher_id = ?", teacherID).Count(&count).Error
+	return count, err
+}
+
+func (r gormCourses) HasMarks(ctx context.Context, courseID uint) (bool, error) {
+	var grades, scores int64
+	db := r.db.WithContext(ctx)
+	if err := db.Model(&models.Grade{}).Where("course_id = ?", courseID).Count(&grades).Error; err != nil {
+		return false, err
+	}
+	err := db.Model(&models.ComponentScore{}).
+		Joins("JOIN assessment_components ON assessment_components.id = component_scores.component_id").
+		Where("assessment_components.course_id = ?", courseID).Count(&scores).Error
+	return grades > 0 || scores > 0, err
+}
+
+func (r gormCourses) RemoveAssistant(ctx context.Context, courseID, userID uint) error {
+	return r.db.WithContext(ctx).Where("course_id = ? AND user_id = ?", courseID, userID).Delete(&models.CourseAssistant{}).Error
+}
+
+func (r gormCourses) FindComponent(ctx context.Context, courseID, componentID uint) (models.AssessmentComponent, error) {
+	var component models.AssessmentComponent
+	err := r.db.WithContext(ctx).Where("id = ? AND course_id = ?", componentID, courseID).First(&component).Error
+	return component, notFound(err)
+}
+
+func (r gormCourses) ListComponents(ctx context.Context, courseID uint) ([]models.AssessmentComponent, error) {
+	var components []models.AssessmentComponent
+	err := r.db.WithContext(ctx).Where("course_id = ?", courseID).Order("id").Find(&components).Error
+	return components, err
+}
+
+type gormEnrollments struct {
+	db *gorm.DB
+}
+
+func (r gormEnrollments) Exists(ctx context.Context, studentID, courseID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Enrollment{}).Where("student_id = ? AND course_id = ?", studentID, courseID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r gormEnrollments) Create(ctx context.Context, enrollment *models.Enrollment) error {
+	return duplicate(r.db, r.db.WithContext(ctx).Create(enrollment).Error)
+}
+
+func (r gormEnrollments) CreateMany(ctx context.Context, courseID uint, studentIDs []uint) (map[uint]bool, error) {
+	inserted := map[uint]bool{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(studentIDs); start += 500 {
+			batch := studentIDs[start:min(start+500, len(studentIDs))]
+			rows := make([]string, len(batch))
+			args := make([]interface{}, 0, 2*len(batch))
+			for i, studentID := range batch {
+				rows[i] = "(?, ?)"
+				args = append(args, studentID, courseID)
+			}
+
+			// RETURNING only yields the rows that were not skipped as conflicts
+			var returned []uint
+			err := tx.Raw("INSERT INTO enrollments (student_id, course_id) VALUES "+strings.Join(rows, ", ")+
+				" ON CONFLICT (student_id, course_id) DO NOTHING RETURNING student_id", args...).Scan(&returned).Error
+			if err != nil {
+				return err
+			}
+			for _, studentID := range returned {
+				inserted[studentID] = true
+			}
+		}
+		return nil
+	})
+	return inserted, err
+}
+
+func (r gormEnrollments) StudentIDs(ctx context.Context, courseID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.Enrollment{}).Where("course_id = ?", courseID).Pluck("student_id", &ids).Error
+	return ids, err
+}
+
+func (r gormEnrollments) Count(ctx context.Context, courseID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Enrollment{}).Where("course_id = ?", courseID).Count(&count).Error
+	return count, err
+}
+
+func (r gormEnrollments) CoursesOf(ctx context.Context, studentID uint) ([]models.Course, error) {
+	var enrollments []models.Enrollment
+	if err := r.db.WithContext(ctx).Preload("Course").Where("student_id = ?", studentID).Find(&enrollments).Error; err != nil {
+		return nil, err
+	}
+
+	var courses []models.Course
+	for _, e := range enrollments {
+		courses = append(courses, e.Course)
+	}
+	return courses, nil
+}
+
+type gormGrades struct {
+	db *gorm.DB
+}
+
+func (r gormGrades) Find(ctx context.Context, studentID, courseID uint) (models.Grade, error) {
+	var grade models.Grade
+	err := r.db.WithContext(ctx).Where("student_id = ? AND course_id = ?", studentID, courseID).First(&grade).Error
+	return grade, notFound(err)
+}
+
+func (r gormGrades) ListByStudent(ctx context.Context, studentID uint) ([]models.Grade, error) {
+	var grades []models.Grade
+	err := r.db.WithContext(ctx).Preload("Course").Where("student_id = ?", studentID).Find(&grades).Error
+	return grades, err
+}
+
+func (r gormGrades) Save(ctx context.Context, grade *models.Grade) error {
+	return r.db.WithContext(ctx).Save(grade).Error
+}
+
+func (r gormGrades) FindScore(ctx context.Context, componentID, studentID uint) (models.ComponentScore, error) {
+	var score models.ComponentScore
+	err := r.db.WithContext(ctx).Where("component_id = ? AND student_id = ?", componentID, studentID).First(&score).Error
+	return score, notFound(err)
+}
+
+func (r gormGrades) SaveScore(ctx context.Context, score *models.ComponentScore) error {
+	return r.db.WithContext(ctx).Save(score).Error
+}
+
+func (r gormGrades) AddHistory(ctx context.Context, entry *models.GradeHistory) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r gormGrades) DeleteHistory(ctx context.Context, courseID uint) error {
+	return r.db.WithContext(ctx).Where("course_id = ?", courseID).Delete(&models.GradeHistory{}).Error
+}
+
+func (r gormGrades) Roster(ctx context.Context, courseID uint) ([]RosterEntry, error) {
+	var roster []RosterEntry
+	err := r.db.WithContext(ctx).Table("enrollments").
+		Select("users.id AS student_id, users.name, users.email, users.roll_number, grades.marks, COALESCE(grades.grade_letter, '') AS grade_letter").
+		Joins("JOIN users ON users.id = enrollments.student_id AND users.deleted_at IS NULL").
+		Joins("LEFT JOIN grades ON grades.student_id = enrollments.student_id AND grades.course_id = enrollments.course_id").
+		Where("enrollments.course_id = ?", courseID).
+		Order("users.name, users.id").
+		Scan(&roster).Error
+	return roster, err
+}
+
+func (r gormGrades) LetterCounts(ctx context.Context, courseID uint) ([]LetterCount, error) {
+	var letters []LetterCount
+	err := r.db.WithContext(ctx).Model(&models.Grade{}).
+		Select("grade_letter, count(id) as count").
+		Where("course_id = ?", courseID).
+		Group("grade_letter").
+		Order("grade_letter").
+		Scan(&letters).Error
+	return letters, err
+}
+
+func (r gormGrades) SummarizeMarks(ctx context.Context, courseID uint, options SummaryOptions) (MarkSummary, error) {
+	marks := r.db.WithContext(ctx).Model(&models.Grade{}).Select("marks AS value").Where("course_id = ?", courseID)
+	return SummarizeValues(marks, options)
+}
+
+func (r gormGrades) SummarizeScores(ctx context.Context, componentID uint, options SummaryOptions) (MarkSummary, error) {
+	scores := r.db.WithContext(ctx).Model(&models.ComponentScore{}).Select("score AS value").Where("component_id = ?", componentID)
+	return SummarizeValues(scores, options)
+}
+
+// SummarizeValues aggregates the "value" column of values, a subquery with one
+// row per graded student. Everything is computed by the database: one pass for
+// the moments, pass count and histogram, and a small ordered lookup per quartile.
+func SummarizeValues(values *gorm.DB, options SummaryOptions) (MarkSummary, error) {
+	db := values.Session(&gorm.Session{NewDB: true})
+	width := options.Max / float64(options.Bins)
+
+	columns := []string{
+		"COUNT(value) AS graded",
+		"AVG(value) AS mean",
+		"AVG(value * value) AS mean_square",
+		"MIN(value) AS min",
+		"MAX(value) AS max",
+		"SUM(CASE WHEN value >= ? THEN 1 ELSE 0 END) AS passed",
+	}
+	args := []interface{}{options.PassValue}
+	for i := 0; i < options.Bins; i++ {
+		if i == options.Bins-1 {
+			// The last bin is closed so it includes the maximum
+			columns = append(columns, fmt.Sprintf("SUM(CASE WHEN value >= ? THEN 1 ELSE 0 END) AS bin%d", i))
+			args = append(args, float64(i)*width)
+		} else {
+			columns = append(columns, fmt.Sprintf("SUM(CASE WHEN value >= ? AND value < ? THEN 1 ELSE 0 END) AS bin%d", i))
+			args = append(args, float64(i)*width, float64(i+1)*width)
+		}
+	}
+
+	row := map[string]interface{}{}
+	if err := db.Table("(?) AS v", values).Select(strings.Join(columns, ", "), args...).Take(&row).Error; err != nil {
+		return MarkSummary{}, err
+	}
+
+	summary := MarkSummary{Count: toInt64(row["graded"]), Bins: make([]int64, options.Bins)}
+	for i := range summary.Bins {
+		summary.Bins[i] = toInt64(row[fmt.Sprintf("bin%d", i)])
+	}
+	if summary.Count == 0 {
+		return summary, nil
+	}
+	summary.Mean, summary.MeanSquare = toFloat64(row["mean"]), toFloat64(row["mean_square"])
+	summary.Min, summary.Max = toFloat64(row["min"]), toFloat64(row["max"])
+	summary.Passed = toInt64(row["passed"])
+
+	for i, fraction := range []float64{0.25, 0.5, 0.75} {
+		value, err := quantile(db, values, summary.Count, fraction)
+		if err != nil {
+			return summary, err
+		}
+		summary.Quartiles[i] = value
+	}
+	return summary, nil
+}
+
+// quantile interpolates between the two values around the requested fraction
+// of the ordered values, as percentile_cont does, reading only those two rows
+func quantile(db, values *gorm.DB, count int64, fraction float64) (float64, error) {
+	position := fraction * float64(count-1)
+	lower := math.Floor(position)
+
+	var around []float64
+	if err := db.Table("(?) AS v", values).Order("value").Offset(int(lower)).Limit(2).Pluck("value", &around).Error; err != nil {
+		return 0, err
+	}
+	if len(around) == 0 {
+		return 0, fmt.Errorf("no value at position %d", int(lower))
+	}
+	if len(around) == 1 {
+		return around[0], nil
+	}
+	return around[0] + (around[1]-around[0])*(position-lower), nil
+}
+
+// toInt64 and toFloat64 read aggregate results, whose Go type depends on the
+// database driver
+func toInt64(value interface{}) int64 {
+	return int64(toFloat64(value))
+}
+
+func toFloat64(value interface{}) float64 {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case []byte:
+		parsed, _ := strconv.ParseFloat(string(v), 64)
+		return parsed
+	case string:
+		parsed, _ := strconv.ParseFloat(v, 64)
+		return parsed
+	case fmt.Stringer:
+		parsed, _ := strconv.ParseFloat(v.String(), 64)
+		return parsed
+	}
+	return 0
+}
@@ -0,0 +1,575 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"grade-management-system/models"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryStore is a Store that keeps records in memory, for testing services
+// without a database. It enforces the unique constraints the services rely on
+// but no foreign keys. Transactions are rolled back by restoring a snapshot.
+type MemoryStore struct {
+	mu     sync.Mutex
+	lastID uint
+	data   memoryData
+}
+
+type memoryData struct {
+	users       map[uint]models.User
+	courses     map[uint]models.Course
+	components  map[uint]models.AssessmentComponent
+	assistants  []models.CourseAssistant
+	enrollments []models.Enrollment
+	grades      map[uint]models.Grade
+	scores      map[uint]models.ComponentScore
+	history     []models.GradeHistory
+}
+
+func (d memoryData) clone() memoryData {
+	return memoryData{
+		users:       maps.Clone(d.users),
+		courses:     maps.Clone(d.courses),
+		components:  maps.Clone(d.components),
+		assistants:  slices.Clone(d.assistants),
+		enrollments: slices.Clone(d.enrollments),
+		grades:      maps.Clone(d.grades),
+		scores:      maps.Clone(d.scores),
+		history:     slices.Clone(d.history),
+	}
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: memoryData{
+		users:      map[uint]models.User{},
+		courses:    map[uint]models.Course{},
+		components: map[uint]models.AssessmentComponent{},
+		grades:     map[uint]models.Grade{},
+		scores:     map[uint]models.ComponentScore{},
+	}}
+}
+
+func (s *MemoryStore) Users() UserRepository             { return memoryUsers{s} }
+func (s *MemoryStore) Courses() CourseRepository         { return memoryCourses{s} }
+func (s *MemoryStore) Enrollments() EnrollmentRepository { return memoryEnrollments{s} }
+func (s *MemoryStore) Grades() GradeRepository           { return memoryGrades{s} }
+
+func (s *MemoryStore) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	s.mu.Lock()
+	snapshot, lastID := s.data.clone(), s.lastID
+	s.mu.Unlock()
+
+	err := fn(s)
+	if err != nil {
+		s.mu.Lock()
+		s.data, s.lastID = snapshot, lastID
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// AddComponent stores an assessment component, which no repository creates
+func (s *MemoryStore) AddComponent(component *models.AssessmentComponent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	component.ID = s.nextID()
+	s.data.components[component.ID] = *component
+}
+
+// History returns the grade history recorded so far, oldest first
+func (s *MemoryStore) History() []models.GradeHistory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.data.history)
+}
+
+func (s *MemoryStore) nextID() uint {
+	s.lastID++
+	return s.lastID
+}
+
+// copyFields copies the named struct fields of src into dst
+func copyFields(dst, src interface{}, fields []string) {
+	to, from := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for _, field := range fields {
+		to.FieldByName(field).Set(from.FieldByName(field))
+	}
+}
+
+type memoryUsers struct {
+	s *MemoryStore
+}
+
+func (r memoryUsers) FindByID(ctx context.Context, id uint) (models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.data.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return models.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+func (r memoryUsers) FindByIDsOrEmails(ctx context.Context, ids []uint, emails []string) ([]models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var users []models.User
+	for _, user := range r.s.data.users {
+		if !user.DeletedAt.Valid && (slices.Contains(ids, user.ID) || slices.Contains(emails, user.Email)) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r memoryUsers) ActiveStudentIDs(ctx context.Context, department string, courseID uint) ([]uint, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var ids []uint
+	for _, user := range r.s.data.users {
+		if user.DeletedAt.Valid || user.DeactivatedAt != nil || user.Role != models.RoleStudent {
+			continue
+		}
+		if department != "" && user.Department != department {
+			continue
+		}
+		if courseID != 0 && !r.s.enrolled(user.ID, courseID) {
+			continue
+		}
+		ids = append(ids, user.ID)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (r memoryUsers) EmailInUse(ctx context.Context, email string, exceptID uint) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, user := range r.s.data.users {
+		if user.Email == email && user.ID != exceptID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memoryUsers) Create(ctx context.Context, user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.data.users {
+		if existing.Email == user.Email {
+			return ErrDuplicate
+		}
+	}
+	user.ID = r.s.nextID()
+	user.CreatedAt = time.Now()
+	r.s.data.users[user.ID] = *user
+	return nil
+}
+
+func (r memoryUsers) Update(ctx context.Context, user *models.User, fields ...string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored, ok := r.s.data.users[user.ID]
+	if !ok {
+		return nil
+	}
+	copyFields(&stored, user, fields)
+	r.s.data.users[user.ID] = stored
+	return nil
+}
+
+func (r memoryUsers) Delete(ctx context.Context, user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if stored, ok := r.s.data.users[user.ID]; ok {
+		stored.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		r.s.data.users[user.ID] = stored
+	}
+	return nil
+}
+
+type memoryCourses struct {
+	s *MemoryStore
+}
+
+func (r memoryCourses) FindByID(ctx context.Context, id uint) (models.Course, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	course, ok := r.s.data.courses[id]
+	if !ok {
+		return models.Course{}, ErrNotFound
+	}
+	return course, nil
+}
+
+func (r memoryCourses) Create(ctx context.Context, course *models.Course) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.data.courses {
+		if existing.Name == course.Name {
+			return ErrDuplicate
+		}
+	}
+	course.ID = r.s.nextID()
+	course.CreatedAt = time.Now()
+	course.UpdatedAt = course.CreatedAt
+	r.s.data.courses[course.ID] = *course
+	return nil
+}
+
+func (r memoryCourses) Update(ctx context.Context, course *models.Course, fields ...string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored, ok := r.s.data.courses[course.ID]
+	if !ok {
+		return nil
+	}
+	copyFields(&stored, course, fields)
+	stored.UpdatedAt = time.Now()
+	r.s.data.courses[course.ID] = stored
+	return nil
+}
+
+// Delete removes the course with what the database cascades to: its
+// enrollments, grades, components, scores and assistants
+func (r memoryCourses) Delete(ctx context.Context, course *models.Course) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.data.courses, course.ID)
+	r.s.data.enrollments = slices.DeleteFunc(r.s.data.enrollments, func(e models.Enrollment) bool { return e.CourseID == course.ID })
+	r.s.data.assistants = slices.DeleteFunc(r.s.data.assistants, func(a models.CourseAssistant) bool { return a.CourseID == course.ID })
+	maps.DeleteFunc(r.s.data.grades, func(_ uint, g models.Grade) bool { return g.CourseID == course.ID })
+	maps.DeleteFunc(r.s.data.scores, func(_ uint, score models.ComponentScore) bool {
+		return r.s.data.components[score.ComponentID].CourseID == course.ID
+	})
+	maps.DeleteFunc(r.s.data.components, func(_ uint, c models.AssessmentComponent) bool { return c.CourseID == course.ID })
+	return nil
+}
+
+func (r memoryCourses) CountByTeacher(ctx context.Context, teacherID uint) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var count int64
+	for _, course := range r.s.data.courses {
+		if course.TeacherID == teacherID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r memoryCourses) HasMarks(ctx context.Context, courseID uint) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, grade := range r.s.data.grades {
+		if grade.CourseID == courseID {
+			return true, nil
+		}
+	}
+	for _, score := range r.s.data.scores {
+		if r.s.data.components[score.ComponentID].CourseID == courseID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memoryCourses) RemoveAssistant(ctx context.Context, courseID, userID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.data.assistants = slices.DeleteFunc(r.s.data.assistants, func(a models.CourseAssistant) bool {
+		return a.CourseID == courseID && a.UserID == userID
+	})
+	return nil
+}
+
+func (r memoryCourses) FindComponent(ctx context.Context, courseID, componentID uint) (models.AssessmentComponent, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	component, ok := r.s.data.components[componentID]
+	if !ok || component.CourseID != courseID {
+		return models.AssessmentComponent{}, ErrNotFound
+	}
+	return component, nil
+}
+
+func (r memoryCourses) ListComponents(ctx context.Context, courseID uint) ([]models.AssessmentComponent, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var components []models.AssessmentComponent
+	for _, component := range r.s.data.components {
+		if component.CourseID == courseID {
+			components = append(components, component)
+		}
+	}
+	slices.SortFunc(components, func(a, b models.AssessmentComponent) int { return cmp.Compare(a.ID, b.ID) })
+	return components, nil
+}
+
+type memoryEnrollments struct {
+	s *MemoryStore
+}
+
+// enrolled reports whether the student is enrolled in the course; the caller holds the lock
+func (s *MemoryStore) enrolled(studentID, courseID uint) bool {
+	return slices.ContainsFunc(s.data.enrollments, func(e models.Enrollment) bool {
+		return e.StudentID == studentID && e.CourseID == courseID
+	})
+}
+
+func (r memoryEnrollments) Exists(ctx context.Context, studentID, courseID uint) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.enrolled(studentID, courseID), nil
+}
+
+func (r memoryEnrollments) Create(ctx context.Context, enrollment *models.Enrollment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.enrolled(enrollment.StudentID, enrollment.CourseID) {
+		return ErrDuplicate
+	}
+	enrollment.ID = r.s.nextID()
+	r.s.data.enrollments = append(r.s.data.enrollments, *enrollment)
+	return nil
+}
+
+func (r memoryEnrollments) CreateMany(ctx context.Context, courseID uint, studentIDs []uint) (map[uint]bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	inserted := map[uint]bool{}
+	for _, studentID := range studentIDs {
+		if r.s.enrolled(studentID, courseID) {
+			continue
+		}
+		r.s.data.enrollments = append(r.s.data.enrollments, models.Enrollment{ID: r.s.nextID(), StudentID: studentID, CourseID: courseID})
+		inserted[studentID] = true
+	}
+	return inserted, nil
+}
+
+func (r memoryEnrollments) StudentIDs(ctx context.Context, courseID uint) ([]uint, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var ids []uint
+	for _, enrollment := range r.s.data.enrollments {
+		if enrollment.CourseID == courseID {
+			ids = append(ids, enrollment.StudentID)
+		}
+	}
+	return ids, nil
+}
+
+func (r memoryEnrollments) Count(ctx context.Context, courseID uint) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var count int64
+	for _, enrollment := range r.s.data.enrollments {
+		if enrollment.CourseID == courseID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r memoryEnrollments) CoursesOf(ctx context.Context, studentID uint) ([]models.Course, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var courses []models.Course
+	for _, enrollment := range r.s.data.enrollments {
+		if enrollment.StudentID == studentID {
+			courses = append(courses, r.s.data.courses[enrollment.CourseID])
+		}
+	}
+	return courses, nil
+}
+
+type memoryGrades struct {
+	s *MemoryStore
+}
+
+func (r memoryGrades) Find(ctx context.Context, studentID, courseID uint) (models.Grade, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, grade := range r.s.data.grades {
+		if grade.StudentID == studentID && grade.CourseID == courseID {
+			return grade, nil
+		}
+	}
+	return models.Grade{}, ErrNotFound
+}
+
+func (r memoryGrades) ListByStudent(ctx context.Context, studentID uint) ([]models.Grade, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var grades []models.Grade
+	for _, grade := range r.s.data.grades {
+		if grade.StudentID == studentID {
+			grade.Course = r.s.data.courses[grade.CourseID]
+			grades = append(grades, grade)
+		}
+	}
+	sort.Slice(grades, func(i, j int) bool { return grades[i].ID < grades[j].ID })
+	return grades, nil
+}
+
+func (r memoryGrades) Save(ctx context.Context, grade *models.Grade) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if grade.ID == 0 {
+		grade.ID = r.s.nextID()
+	}
+	r.s.data.grades[grade.ID] = *grade
+	return nil
+}
+
+func (r memoryGrades) FindScore(ctx context.Context, componentID, studentID uint) (models.ComponentScore, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, score := range r.s.data.scores {
+		if score.ComponentID == componentID && score.StudentID == studentID {
+			return score, nil
+		}
+	}
+	return models.ComponentScore{}, ErrNotFound
+}
+
+func (r memoryGrades) SaveScore(ctx context.Context, score *models.ComponentScore) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if score.ID == 0 {
+		score.ID = r.s.nextID()
+	}
+	score.UpdatedAt = time.Now()
+	r.s.data.scores[score.ID] = *score
+	return nil
+}
+
+func (r memoryGrades) AddHistory(ctx context.Context, entry *models.GradeHistory) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	entry.ID = r.s.nextID()
+	entry.CreatedAt = time.Now()
+	r.s.data.history = append(r.s.data.history, *entry)
+	return nil
+}
+
+func (r memoryGrades) DeleteHistory(ctx context.Context, courseID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.data.history = slices.DeleteFunc(r.s.data.history, func(h models.GradeHistory) bool { return h.CourseID == courseID })
+	return nil
+}
+
+func (r memoryGrades) Roster(ctx context.Context, courseID uint) ([]RosterEntry, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var roster []RosterEntry
+	for _, enrollment := range r.s.data.enrollments {
+		user, ok := r.s.data.users[enrollment.StudentID]
+		if enrollment.CourseID != courseID || !ok || user.DeletedAt.Valid {
+			continue
+		}
+		entry := RosterEntry{StudentID: user.ID, Name: user.Name, Email: user.Email, RollNumber: user.RollNumber}
+		for _, grade := range r.s.data.grades {
+			if grade.StudentID == user.ID && grade.CourseID == courseID {
+				marks := grade.Marks
+				entry.Marks, entry.GradeLetter = &marks, grade.GradeLetter
+			}
+		}
+		roster = append(roster, entry)
+	}
+	slices.SortFunc(roster, func(a, b RosterEntry) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.StudentID, b.StudentID))
+	})
+	return roster, nil
+}
+
+func (r memoryGrades) LetterCounts(ctx context.Context, courseID uint) ([]LetterCount, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	counts := map[string]int{}
+	for _, grade := range r.s.data.grades {
+		if grade.CourseID == courseID {
+			counts[grade.GradeLetter]++
+		}
+	}
+	var letters []LetterCount
+	for _, letter := range slices.Sorted(maps.Keys(counts)) {
+		letters = append(letters, LetterCount{GradeLetter: letter, Count: counts[letter]})
+	}
+	return letters, nil
+}
+
+func (r memoryGrades) SummarizeMarks(ctx context.Context, courseID uint, options SummaryOptions) (MarkSummary, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var marks []float64
+	for _, grade := range r.s.data.grades {
+		if grade.CourseID == courseID {
+			marks = append(marks, grade.Marks)
+		}
+	}
+	return summarize(marks, options), nil
+}
+
+func (r memoryGrades) SummarizeScores(ctx context.Context, componentID uint, options SummaryOptions) (MarkSummary, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var scores []float64
+	for _, score := range r.s.data.scores {
+		if score.ComponentID == componentID {
+			scores = append(scores, score.Score)
+		}
+	}
+	return summarize(scores, options), nil
+}
+
+// summarize computes in Go what SummarizeValues asks of the database
+func summarize(values []float64, options SummaryOptions) MarkSummary {
+	summary := MarkSummary{Count: int64(len(values)), Bins: make([]int64, options.Bins)}
+	if len(values) == 0 {
+		return summary
+	}
+	slices.Sort(values)
+
+	width := options.Max / float64(options.Bins)
+	var sum, sumOfSquares float64
+	for _, value := range values {
+		sum += value
+		sumOfSquares += value * value
+		if value >= options.PassValue {
+			summary.Passed++
+		}
+		for i := range summary.Bins {
+			if value >= float64(i)*width && (i == options.Bins-1 || value < float64(i+1)*width) {
+				summary.Bins[i]++
+				break
+			}
+		}
+	}
+	summary.Mean = sum / float64(len(values))
+	summary.MeanSquare = sumOfSquares / float64(len(values))
+	summary.Min, summary.Max = values[0], values[len(values)-1]
+
+	for i, fraction := range []float64{0.25, 0.5, 0.75} {
+		position := fraction * float64(len(values)-1)
+		lower := int(math.Floor(position))
+		summary.Quartiles[i] = values[lower]
+		if lower+1 < len(values) {
+			summary.Quartiles[i] += (values[lower+1] - values[lower]) * (position - float64(lower))
+		}
+	}
+	return summary
+}
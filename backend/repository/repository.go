@@ -0,0 +1,133 @@
+// Package repository is the persistence layer of the domain services. Each
+// repository hides the queries for one kind of record behind an interface, so
+// services can run against the database or against in-memory fakes.
+package repository
+
+import (
+	"context"
+	"errors"
+	"grade-management-system/models"
+)
+
+// ErrNotFound is returned when the requested record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// ErrDuplicate is returned when a record clashes with a unique constraint
+var ErrDuplicate = errors.New("duplicate record")
+
+// Store gives access to all repositories. Transaction runs fn with repositories
+// bound to one transaction, committed when fn returns nil and rolled back otherwise.
+type Store interface {
+	Users() UserRepository
+	Courses() CourseRepository
+	Enrollments() EnrollmentRepository
+	Grades() GradeRepository
+	Transaction(ctx context.Context, fn func(tx Store) error) error
+}
+
+// UserRepository stores user accounts
+type UserRepository interface {
+	FindByID(ctx context.Context, id uint) (models.User, error)
+	// FindByIDsOrEmails returns the users with any of the IDs or emails
+	FindByIDsOrEmails(ctx context.Context, ids []uint, emails []string) ([]models.User, error)
+	// ActiveStudentIDs returns the active students of a department and, when
+	// courseID is not zero, enrolled in that course, ordered by ID
+	ActiveStudentIDs(ctx context.Context, department string, courseID uint) ([]uint, error)
+	// EmailInUse reports whether another user, deleted ones included, has the email
+	EmailInUse(ctx context.Context, email string, exceptID uint) (bool, error)
+	Create(ctx context.Context, user *models.User) error
+	// Update writes the named fields of the user
+	Update(ctx context.Context, user *models.User, fields ...string) error
+	Delete(ctx context.Context, user *models.User) error
+}
+
+// CourseRepository stores courses and their assistants
+type CourseRepository interface {
+	FindByID(ctx context.Context, id uint) (models.Course, error)
+	Create(ctx context.Context, course *models.Course) error
+	// Update writes the named fields of the course
+	Update(ctx context.Context, course *models.Course, fields ...string) error
+	Delete(ctx context.Context, course *models.Course) error
+	CountByTeacher(ctx context.Context, teacherID uint) (int64, error)
+	// HasMarks reports whether anyone has a grade or a component score in the course
+	HasMarks(ctx context.Context, courseID uint) (bool, error)
+	RemoveAssistant(ctx context.Context, courseID, userID uint) error
+	// FindComponent returns an assessment component of the course
+	FindComponent(ctx context.Context, courseID, componentID uint) (models.AssessmentComponent, error)
+	// ListComponents returns the assessment components of the course, ordered by ID
+	ListComponents(ctx context.Context, courseID uint) ([]models.AssessmentComponent, error)
+}
+
+// EnrollmentRepository stores the enrollments of students in courses
+type EnrollmentRepository interface {
+	Exists(ctx context.Context, studentID, courseID uint) (bool, error)
+	// Create returns ErrDuplicate when the student is already enrolled
+	Create(ctx context.Context, enrollment *models.Enrollment) error
+	// CreateMany enrolls the students, skipping those already enrolled, and
+	// returns the students actually enrolled
+	CreateMany(ctx context.Context, courseID uint, studentIDs []uint) (map[uint]bool, error)
+	// StudentIDs returns the students enrolled in the course
+	StudentIDs(ctx context.Context, courseID uint) ([]uint, error)
+	// Count returns the number of enrollments in the course
+	Count(ctx context.Context, courseID uint) (int64, error)
+	// CoursesOf returns the courses the student is enrolled in
+	CoursesOf(ctx context.Context, studentID uint) ([]models.Course, error)
+}
+
+// GradeRepository stores final grades, component scores and their history
+type GradeRepository interface {
+	Find(ctx context.Context, studentID, courseID uint) (models.Grade, error)
+	// ListByStudent returns the student's grades with their courses
+	ListByStudent(ctx context.Context, studentID uint) ([]models.Grade, error)
+	Save(ctx context.Context, grade *models.Grade) error
+	FindScore(ctx context.Context, componentID, studentID uint) (models.ComponentScore, error)
+	SaveScore(ctx context.Context, score *models.ComponentScore) error
+	AddHistory(ctx context.Context, entry *models.GradeHistory) error
+	DeleteHistory(ctx context.Context, courseID uint) error
+	// Roster returns the students enrolled in the course, deleted ones left
+	// out, with their grades, ordered by name
+	Roster(ctx context.Context, courseID uint) ([]RosterEntry, error)
+	// LetterCounts counts the final grades of the course per letter, in letter order
+	LetterCounts(ctx context.Context, courseID uint) ([]LetterCount, error)
+	// SummarizeMarks aggregates the final marks of the course
+	SummarizeMarks(ctx context.Context, courseID uint, options SummaryOptions) (MarkSummary, error)
+	// SummarizeScores aggregates the scores entered for an assessment component
+	SummarizeScores(ctx context.Context, componentID uint, options SummaryOptions) (MarkSummary, error)
+}
+
+// RosterEntry is a student enrolled in a course with their final grade, if any
+type RosterEntry struct {
+	StudentID   uint
+	Name        string
+	Email       string
+	RollNumber  *string
+	Marks       *float64
+	GradeLetter string
+}
+
+// LetterCount is the number of final grades with a letter
+type LetterCount struct {
+	GradeLetter string `json:"grade_letter"`
+	Count       int    `json:"count"`
+}
+
+// SummaryOptions shape a MarkSummary: values at or above PassValue pass, and
+// the range from 0 to Max is split into Bins equal-width histogram bins
+type SummaryOptions struct {
+	Max       float64
+	PassValue float64
+	Bins      int
+}
+
+// MarkSummary aggregates a set of marks or scores. Only Bins is filled in
+// when Count is zero.
+type MarkSummary struct {
+	Count      int64
+	Mean       float64
+	MeanSquare float64
+	Min        float64
+	Max        float64
+	Passed     int64
+	Bins       []int64    // Values per histogram bin; the last bin includes its upper bound
+	Quartiles  [3]float64 // Q1, median and Q3, interpolated as percentile_cont does
+}
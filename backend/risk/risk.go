@@ -0,0 +1,348 @@
+// Package risk is the early-warning engine. It checks students against the
+// admin-configured RiskRules and keeps their RiskFlags up to date, both on a
+// schedule and whenever grades, scores or attendance change.
+package risk
+
+import (
+	"context"
+	"fmt"
+	"grade-management-system/models"
+	"grade-management-system/services"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// finding is a rule match for a student, in a course unless CourseID is nil
+type finding struct {
+	StudentID uint
+	CourseID  *uint
+	Rule      string
+	Reason    string
+}
+
+func (f finding) key() string {
+	return flagKey(f.StudentID, f.CourseID, f.Rule)
+}
+
+func flagKey(studentID uint, courseID *uint, rule string) string {
+	if courseID == nil {
+		return fmt.Sprintf("%d/-/%s", studentID, rule)
+	}
+	return fmt.Sprintf("%d/%d/%s", studentID, *courseID, rule)
+}
+
+// Evaluate checks the given students, or every student when studentIDs is nil,
+// against the enabled rules. New matches raise flags, changed matches update
+// their reason and flags that no longer match are resolved. Its queries run
+// with ctx, so they are traced as part of the request that triggered them.
+func Evaluate(ctx context.Context, db *gorm.DB, studentIDs []uint) error {
+	if studentIDs != nil && len(studentIDs) == 0 {
+		return nil
+	}
+	db = db.WithContext(ctx)
+
+	var rules []models.RiskRule
+	if err := db.Where("enabled = ?", true).Find(&rules).Error; err != nil {
+		return err
+	}
+
+	var findings []finding
+	for _, rule := range rules {
+		var (
+			matches []finding
+			err     error
+		)
+		switch rule.Key {
+		case models.RiskRuleFailingComponents:
+			matches, err = failingComponents(db, studentIDs, rule)
+		case models.RiskRuleLowAttendance:
+			matches, err = lowAttendance(db, studentIDs, rule)
+		case models.RiskRuleGPADrop:
+			matches, err = gpaDrop(db, studentIDs, rule)
+		case models.RiskRuleMissingAssessments:
+			matches, err = missingAssessments(db, studentIDs, rule)
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("rule %s: %w", rule.Key, err)
+		}
+		findings = append(findings, matches...)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var active []models.RiskFlag
+		if err := scoped(tx.Where("resolved_at IS NULL"), "student_id", studentIDs).Find(&active).Error; err != nil {
+			return err
+		}
+		existing := make(map[string]models.RiskFlag, len(active))
+		for _, flag := range active {
+			existing[flagKey(flag.StudentID, flag.CourseID, flag.RuleKey)] = flag
+		}
+
+		now := time.Now()
+		for _, match := range findings {
+			flag, ok := existing[match.key()]
+			delete(existing, match.key())
+			switch {
+			case !ok:
+				// A concurrent evaluation may have raised the same flag since it was read
+				flag = models.RiskFlag{StudentID: match.StudentID, CourseID: match.CourseID, RuleKey: match.Rule, Reason: match.Reason, RaisedAt: now}
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&flag).Error; err != nil {
+					return err
+				}
+			case flag.Reason != match.Reason:
+				if err := tx.Model(&flag).Update("reason", match.Reason).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// Whatever is left no longer matches an enabled rule
+		for _, flag := range existing {
+			if err := tx.Model(&flag).Update("resolved_at", now).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// queue holds students waiting for re-evaluation by the worker. It is
+// bounded so a burst of changes cannot pile up work without limit.
+var queue = make(chan []uint, 256)
+
+// Enqueue re-evaluates students whose grades, scores or attendance just
+// changed, in the background so the request that changed them does not wait.
+// When the queue is full the students are skipped and the scheduled
+// evaluation catches up later.
+func Enqueue(studentIDs ...uint) {
+	if len(studentIDs) == 0 {
+		return
+	}
+	select {
+	case queue <- studentIDs:
+	default:
+		slog.Warn("Risk evaluation queue is full, leaving students to the scheduled evaluation", "student_ids", studentIDs)
+	}
+}
+
+// StartWorker evaluates the students passed to Enqueue, in the background.
+// Students queued while an evaluation runs are evaluated together after it.
+func StartWorker(db *gorm.DB) {
+	go func() {
+		for studentIDs := range queue {
+			pending := map[uint]bool{}
+			for _, id := range studentIDs {
+				pending[id] = true
+			}
+		drain:
+			for {
+				select {
+				case more := <-queue:
+					for _, id := range more {
+						pending[id] = true
+					}
+				default:
+					break drain
+				}
+			}
+
+			ids := make([]uint, 0, len(pending))
+			for id := range pending {
+				ids = append(ids, id)
+			}
+			if err := Evaluate(context.Background(), db, ids); err != nil {
+				slog.Error("Risk evaluation failed", "student_ids", ids, "error", err)
+			}
+		}
+	}()
+}
+
+// scoped restricts a query to the given students when studentIDs is not nil
+func scoped(query *gorm.DB, column string, studentIDs []uint) *gorm.DB {
+	if studentIDs == nil {
+		return query
+	}
+	return query.Where(column+" IN ?", studentIDs)
+}
+
+// failingComponents flags students with at least MinCount component scores
+// below Threshold percent of the component's maximum
+func failingComponents(db *gorm.DB, studentIDs []uint, rule models.RiskRule) ([]finding, error) {
+	var rows []struct {
+		StudentID uint
+		CourseID  uint
+		Failing   int
+	}
+	query := db.Table("component_scores").
+		Select("component_scores.student_id, assessment_components.course_id, COUNT(*) AS failing").
+		Joins("JOIN assessment_components ON assessment_components.id = component_scores.component_id").
+		Joins("JOIN enrollments ON enrollments.student_id = component_scores.student_id AND enrollments.course_id = assessment_components.course_id").
+		Joins("JOIN courses ON courses.id = assessment_components.course_id AND courses.archived_at IS NULL").
+		Where("component_scores.score * 100 < ? * assessment_components.max_score", rule.Threshold).
+		Group("component_scores.student_id, assessment_components.course_id").
+		Having("COUNT(*) >= ?", max(rule.MinCount, 1))
+	if err := scoped(query, "component_scores.student_id", studentIDs).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	findings := make([]finding, 0, len(rows))
+	for _, row := range rows {
+		courseID := row.CourseID
+		findings = append(findings, finding{
+			StudentID: row.StudentID, CourseID: &courseID, Rule: rule.Key,
+			Reason: fmt.Sprintf("%d assessment component(s) scored below %g%%", row.Failing, rule.Threshold),
+		})
+	}
+	return findings, nil
+}
+
+// lowAttendance flags students whose attendance rate in a course is below
+// Threshold percent once MinCount non-excused sessions have been recorded
+func lowAttendance(db *gorm.DB, studentIDs []uint, rule models.RiskRule) ([]finding, error) {
+	var rows []struct {
+		StudentID uint
+		CourseID  uint
+		Counted   int
+		Attended  int
+	}
+	query := db.Table("attendance_records").
+		Select(`attendance_records.student_id, attendance_records.course_id,
+			SUM(CASE WHEN status <> 'excused' THEN 1 ELSE 0 END) AS counted,
+			SUM(CASE WHEN status IN ('present', 'late') THEN 1 ELSE 0 END) AS attended`).
+		Joins("JOIN courses ON courses.id = attendance_records.course_id AND courses.archived_at IS NULL").
+		Group("attendance_records.student_id, attendance_records.course_id").
+		Having("SUM(CASE WHEN status <> 'excused' THEN 1 ELSE 0 END) >= ?", max(rule.MinCount, 1))
+	if err := scoped(query, "attendance_records.student_id", studentIDs).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var findings []finding
+	for _, row := range rows {
+		rate := float64(row.Attended) * 100 / float64(row.Counted)
+		if rate >= rule.Threshold {
+			continue
+		}
+		courseID := row.CourseID
+		findings = append(findings, finding{
+			StudentID: row.StudentID, CourseID: &courseID, Rule: rule.Key,
+			Reason: fmt.Sprintf("Attended %d of %d sessions (%.0f%%, below %g%%)", row.Attended, row.Counted, rate, rule.Threshold),
+		})
+	}
+	return findings, nil
+}
+
+// missingAssessments flags students with no score in at least MinCount
+// components of a course in which other students have already been scored
+func missingAssessments(db *gorm.DB, studentIDs []uint, rule models.RiskRule) ([]finding, error) {
+	var rows []struct {
+		StudentID uint
+		CourseID  uint
+		Missing   int
+	}
+	query := db.Table("enrollments").
+		Select("enrollments.student_id, enrollments.course_id, COUNT(*) AS missing").
+		Joins("JOIN courses ON courses.id = enrollments.course_id AND courses.archived_at IS NULL").
+		Joins("JOIN assessment_components ON assessment_components.course_id = enrollments.course_id").
+		Joins("LEFT JOIN component_scores ON component_scores.component_id = assessment_components.id AND component_scores.student_id = enrollments.student_id").
+		Where("component_scores.id IS NULL").
+		Where("EXISTS (SELECT 1 FROM component_scores AS held WHERE held.component_id = assessment_components.id)").
+		Group("enrollments.student_id, enrollments.course_id").
+		Having("COUNT(*) >= ?", max(rule.MinCount, 1))
+	if err := scoped(query, "enrollments.student_id", studentIDs).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	findings := make([]finding, 0, len(rows))
+	for _, row := range rows {
+		courseID := row.CourseID
+		findings = append(findings, finding{
+			StudentID: row.StudentID, CourseID: &courseID, Rule: rule.Key,
+			Reason: fmt.Sprintf("No score in %d assessment component(s) already graded for the class", row.Missing),
+		})
+	}
+	return findings, nil
+}
+
+// gpaDrop flags students whose GPA in their latest graded term is at least
+// Threshold points below the term before. Terms are ordered by
+// models.CompareTerms, since names such as 2025-Fall do not sort as text.
+func gpaDrop(db *gorm.DB, studentIDs []uint, rule models.RiskRule) ([]finding, error) {
+	var rows []struct {
+		StudentID uint
+		Term      string
+		GPA       float64
+	}
+	query := db.Table("grades").
+		Select("grades.student_id, courses.term, AVG(" + services.GradePointsSQL + ") AS gpa").
+		Joins("JOIN courses ON courses.id = grades.course_id").
+		Where("courses.term <> ''").
+		Group("grades.student_id, courses.term")
+	if err := scoped(query, "grades.student_id", studentIDs).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	type termGPA struct {
+		term string
+		gpa  float64
+	}
+	byStudent := map[uint][]termGPA{}
+	for _, row := range rows {
+		byStudent[row.StudentID] = append(byStudent[row.StudentID], termGPA{row.Term, row.GPA})
+	}
+
+	var findings []finding
+	for studentID, history := range byStudent {
+		if len(history) < 2 {
+			continue
+		}
+		sort.Slice(history, func(i, j int) bool { return models.CompareTerms(history[i].term, history[j].term) < 0 })
+		previous, latest := history[len(history)-2], history[len(history)-1]
+		if previous.gpa-latest.gpa < rule.Threshold {
+			continue
+		}
+		findings = append(findings, finding{
+			StudentID: studentID, Rule: rule.Key,
+			Reason: fmt.Sprintf("GPA fell from %.2f in %s to %.2f in %s", previous.gpa, previous.term, latest.gpa, latest.term),
+		})
+	}
+	return findings, nil
+}
+
+// evaluationInterval is how often every student is re-evaluated, configurable
+// with RISK_EVALUATION_INTERVAL (a duration such as 30m; 0 disables it)
+func evaluationInterval() time.Duration {
+	if value := os.Getenv("RISK_EVALUATION_INTERVAL"); value != "" {
+		if interval, err := time.ParseDuration(value); err == nil {
+			return interval
+		}
+		slog.Warn("Invalid RISK_EVALUATION_INTERVAL, using 1h", "value", value)
+	}
+	return time.Hour
+}
+
+// StartScheduler evaluates every student now and then on each interval, in the background
+func StartScheduler(db *gorm.DB) {
+	interval := evaluationInterval()
+	if interval <= 0 {
+		slog.Info("Scheduled risk evaluation is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := Evaluate(context.Background(), db, nil); err != nil {
+				slog.Error("Scheduled risk evaluation failed", "error", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
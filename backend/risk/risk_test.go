@@ -0,0 +1,96 @@
+package risk
+
+import (
+	"context"
+	"grade-management-system/config"
+	"grade-management-system/migrations"
+	"grade-management-system/models"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := config.OpenSQLite(config.SQLiteMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.EnsureDefaultRoles(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.EnsureDefaultRiskRules(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func create(t *testing.T, db *gorm.DB, value interface{}) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGPADropOrdersTermsChronologically(t *testing.T) {
+	db := openTestDB(t)
+
+	teacher := models.User{Name: "Teacher", Email: "teacher@university.edu", Password: "unused", Role: models.RoleTeacher}
+	student := models.User{Name: "Student", Email: "student@university.edu", Password: "unused", Role: models.RoleStudent}
+	create(t, db, &teacher)
+	create(t, db, &student)
+
+	// The later term's course is created first, so course IDs put it first
+	spring := models.Course{Name: "Algorithms", Term: "2026-Spring", TeacherID: teacher.ID}
+	fall := models.Course{Name: "Programming", Term: "2025-Fall", TeacherID: teacher.ID}
+	create(t, db, &spring)
+	create(t, db, &fall)
+	create(t, db, &models.Grade{StudentID: student.ID, CourseID: fall.ID, Marks: 95, GradeLetter: "A"})
+	create(t, db, &models.Grade{StudentID: student.ID, CourseID: spring.ID, Marks: 65, GradeLetter: "D"})
+
+	// Evaluating twice, as overlapping requests can, must not raise the flag twice
+	for i := 0; i < 2; i++ {
+		if err := Evaluate(context.Background(), db, []uint{student.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var flags []models.RiskFlag
+	db.Where("rule_key = ? AND resolved_at IS NULL", models.RiskRuleGPADrop).Find(&flags)
+	if len(flags) != 1 {
+		t.Fatalf("got %d open gpa_drop flags, want 1", len(flags))
+	}
+	if want := "GPA fell from 4.00 in 2025-Fall to 1.00 in 2026-Spring"; flags[0].Reason != want {
+		t.Errorf("reason %q, want %q", flags[0].Reason, want)
+	}
+}
+
+func TestOpenFlagsAreUnique(t *testing.T) {
+	db := openTestDB(t)
+
+	student := models.User{Name: "Student", Email: "student@university.edu", Password: "unused", Role: models.RoleStudent}
+	create(t, db, &student)
+
+	// A term-wide flag has no course, which must not let duplicates through
+	create(t, db, &models.RiskFlag{StudentID: student.ID, RuleKey: models.RiskRuleGPADrop, Reason: "first"})
+	if err := db.Create(&models.RiskFlag{StudentID: student.ID, RuleKey: models.RiskRuleGPADrop, Reason: "second"}).Error; err == nil {
+		t.Fatal("a second open flag for the same rule was created")
+	}
+
+	if err := db.Model(&models.RiskFlag{}).Where("student_id = ?", student.ID).Update("resolved_at", db.NowFunc()).Error; err != nil {
+		t.Fatal(err)
+	}
+	create(t, db, &models.RiskFlag{StudentID: student.ID, RuleKey: models.RiskRuleGPADrop, Reason: "raised again"})
+}
+
+func TestCompareTerms(t *testing.T) {
+	ordered := []string{"2024-Fall", "Winter 2025", "2025-Spring", "Summer 2025", "2025 Autumn", "2026-Spring"}
+	for i := 1; i < len(ordered); i++ {
+		if models.CompareTerms(ordered[i-1], ordered[i]) >= 0 {
+			t.Errorf("%s should come before %s", ordered[i-1], ordered[i])
+		}
+	}
+}
@@ -0,0 +1,603 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"grade-management-system/config"
+	"grade-management-system/controllers"
+	"grade-management-system/migrations"
+	"grade-management-system/models"
+	"grade-management-system/repository"
+	"grade-management-system/services"
+	"grade-management-system/utils"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// testAPI serves the full router on an in-memory SQLite database
+type testAPI struct {
+	*httptest.Server
+	t *testing.T
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_EPHEMERAL_KEY", "true")
+
+	db, err := config.OpenSQLite(config.SQLiteMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.EnsureDefaultRoles(db); err != nil {
+		t.Fatal(err)
+	}
+	config.DB = db
+
+	store := repository.NewGormStore(db)
+	h := controllers.NewHandlers(
+		services.NewGradingService(store, nil),
+		services.NewEnrollmentService(store),
+		services.NewUserService(store),
+		services.NewCourseService(store),
+	)
+	server := httptest.NewServer(SetupRoutes(h))
+	t.Cleanup(server.Close)
+	return &testAPI{Server: server, t: t}
+}
+
+// call sends body as JSON, authenticated with token unless it is empty, and
+// decodes the response's data
+func (api *testAPI) call(method, path, token string, body interface{}) (int, map[string]interface{}) {
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	req, err := http.NewRequest(method, api.URL+path, &payload)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var decoded struct {
+		Data  map[string]interface{} `json:"data"`
+		Error string                 `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	if decoded.Data == nil {
+		decoded.Data = map[string]interface{}{"error": decoded.Error}
+	}
+	return resp.StatusCode, decoded.Data
+}
+
+// must calls the API and fails the test unless it answers with status
+func (api *testAPI) must(status int, method, path, token string, body interface{}) map[string]interface{} {
+	api.t.Helper()
+	got, data := api.call(method, path, token, body)
+	if got != status {
+		api.t.Fatalf("%s %s: got %d %v, want %d", method, path, got, data, status)
+	}
+	return data
+}
+
+// download fetches a file export and returns its status and body
+func (api *testAPI) download(path, token string) (int, string) {
+	api.t.Helper()
+	req, err := http.NewRequest("GET", api.URL+path, nil)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+// list fetches a page of a list endpoint and fails the test unless it succeeds
+func (api *testAPI) list(path, token string) ([]interface{}, utils.Pagination) {
+	api.t.Helper()
+	status, body := api.download(path, token)
+	if status != http.StatusOK {
+		api.t.Fatalf("GET %s: got %d %s, want 200", path, status, body)
+	}
+	var page struct {
+		Data       []interface{}    `json:"data"`
+		Pagination utils.Pagination `json:"pagination"`
+	}
+	if err := json.Unmarshal([]byte(body), &page); err != nil {
+		api.t.Fatalf("GET %s: %v", path, err)
+	}
+	return page.Data, page.Pagination
+}
+
+func (api *testAPI) login(email, password string) string {
+	api.t.Helper()
+	data := api.must(http.StatusOK, "POST", "/login", "", gin.H{"email": email, "password": password})
+	return data["token"].(string)
+}
+
+// admin creates the first admin directly in the database and logs in as them
+func (api *testAPI) admin() string {
+	api.t.Helper()
+	hash, err := utils.HashPassword("admin-password")
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	admin := models.User{Name: "Admin", Email: "admin@university.edu", Password: hash, Role: models.RoleAdmin}
+	if err := config.DB.Create(&admin).Error; err != nil {
+		api.t.Fatal(err)
+	}
+	return api.login("admin@university.edu", "admin-password")
+}
+
+func TestGradingFlow(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.admin()
+
+	teacher := api.must(http.StatusCreated, "POST", "/api/admin/users", adminToken,
+		gin.H{"name": "Anjali Desai", "email": "anjali@university.edu", "password": "teacher-password", "role": models.RoleTeacher})
+	course := api.must(http.StatusCreated, "POST", "/api/admin/courses", adminToken,
+		gin.H{"name": "Algorithms", "term": "2026-Spring", "teacher_id": teacher["id"]})
+	courseID := course["id"]
+
+	const students = 12
+	studentIDs := make([]interface{}, students)
+	for i := range studentIDs {
+		student := api.must(http.StatusCreated, "POST", "/api/admin/users", adminToken,
+			gin.H{"name": fmt.Sprintf("Student %d", i), "email": fmt.Sprintf("student%d@university.edu", i), "password": "student-password", "role": models.RoleStudent})
+		studentIDs[i] = student["id"]
+	}
+	teacherToken := api.login("anjali@university.edu", "teacher-password")
+
+	// Concurrent writes share the one in-memory connection instead of failing
+	// with SQLITE_LOCKED
+	concurrently := func(path string, body func(studentID interface{}) gin.H, want int) {
+		t.Helper()
+		var wg sync.WaitGroup
+		statuses := make([]int, students)
+		for i, studentID := range studentIDs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				statuses[i], _ = api.call("POST", path, teacherToken, body(studentID))
+			}()
+		}
+		wg.Wait()
+		for i, status := range statuses {
+			if status != want {
+				t.Errorf("POST %s for student %v: got %d, want %d", path, studentIDs[i], status, want)
+			}
+		}
+	}
+	concurrently("/api/teacher/enrollments", func(studentID interface{}) gin.H {
+		return gin.H{"student_id": studentID, "course_id": courseID}
+	}, http.StatusCreated)
+	concurrently("/api/teacher/grades", func(studentID interface{}) gin.H {
+		return gin.H{"student_id": studentID, "course_id": courseID, "marks": 85}
+	}, http.StatusCreated)
+
+	data := api.must(http.StatusBadRequest, "POST", "/api/teacher/enrollments", teacherToken,
+		gin.H{"student_id": studentIDs[0], "course_id": courseID})
+	if data["error"] != "Student is already enrolled in this course" {
+		t.Errorf("enrolling twice: got %q", data["error"])
+	}
+
+	studentToken := api.login("student0@university.edu", "student-password")
+	gpa := api.must(http.StatusOK, "GET", "/api/student/gpa", studentToken, nil)
+	if gpa["gpa"] != 3.0 {
+		t.Errorf("GPA of a B student is %v, want 3", gpa["gpa"])
+	}
+}
+
+func TestExportStudentRecord(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.admin()
+
+	teacher := models.User{Name: "Teacher", Email: "teacher@university.edu", Password: "unused", Role: models.RoleTeacher}
+	student := models.User{Name: "Student", Email: "student@university.edu", Password: "unused", Role: models.RoleStudent}
+	config.DB.Create(&teacher)
+	config.DB.Create(&student)
+	// Created out of order, and 2025-Fall sorts before 2025-Spring as text
+	for _, term := range []string{"2026-Spring", "2025-Fall", "2025-Spring"} {
+		course := models.Course{Name: "Course " + term, Term: term, TeacherID: teacher.ID}
+		config.DB.Create(&course)
+		config.DB.Create(&models.Enrollment{StudentID: student.ID, CourseID: course.ID})
+	}
+
+	for _, path := range []string{fmt.Sprintf("/api/admin/students/%d/export", student.ID), "/api/admin/exports/grades"} {
+		status, body := api.download(path, adminToken)
+		if status != http.StatusOK {
+			t.Fatalf("GET %s: got %d %s", path, status, body)
+		}
+		spring, fall, next := strings.Index(body, "2025-Spring"), strings.Index(body, "2025-Fall"), strings.Index(body, "2026-Spring")
+		if spring < 0 || !(spring < fall && fall < next) {
+			t.Errorf("GET %s does not list terms chronologically:\n%s", path, body)
+		}
+	}
+
+	// A path ID that is not a number must never reach the query
+	injected := "/api/admin/students/" + url.PathEscape(fmt.Sprintf("0 OR id=%d", student.ID)) + "/export"
+	if status, body := api.download(injected, adminToken); status != http.StatusBadRequest {
+		t.Errorf("GET %s: got %d %s, want 400", injected, status, body)
+	}
+}
+
+// TestPathIDsAreNumbers sends SQL in place of path IDs, which GORM would run
+// as a condition if a handler passed the raw parameter to First
+func TestPathIDsAreNumbers(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.admin()
+	if err := models.EnsureDefaultRiskRules(config.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	injected := url.PathEscape("0 OR 1=1")
+	requests := []struct {
+		method, path string
+		body         gin.H
+	}{
+		{"PUT", "/api/admin/risk-rules/" + injected, gin.H{"enabled": false}},
+		{"DELETE", "/api/admin/guardian-links/" + injected, nil},
+		{"PUT", "/api/admin/users/" + injected + "/guardian-settings", gin.H{"guardian_revocable": true}},
+	}
+	for _, req := range requests {
+		if status, data := api.call(req.method, req.path, adminToken, req.body); status != http.StatusBadRequest {
+			t.Errorf("%s %s: got %d %v, want 400", req.method, req.path, status, data)
+		}
+	}
+}
+
+func TestCreateGuardianLinkReportsLookupFailures(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.admin()
+
+	guardian := models.User{Name: "Guardian", Email: "guardian@example.com", Password: "unused", Role: models.RoleGuardian}
+	student := models.User{Name: "Student", Email: "student@university.edu", Password: "unused", Role: models.RoleStudent}
+	config.DB.Create(&guardian)
+	config.DB.Create(&student)
+
+	// Reading existing links fails, as it would when the database drops the connection
+	err := config.DB.Callback().Query().Before("gorm:query").Register("test:fail_guardian_links", func(db *gorm.DB) {
+		if db.Statement.Table == "guardian_links" {
+			db.AddError(errors.New("connection lost"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api.must(http.StatusInternalServerError, "POST", "/api/admin/guardian-links", adminToken,
+		gin.H{"guardian_id": guardian.ID, "student_id": student.ID})
+	var links int64
+	config.DB.Model(&models.GuardianLink{}).Count(&links)
+	if links != 0 {
+		t.Errorf("%d guardian links were created after the lookup failed", links)
+	}
+}
+
+func TestIntegrationListsArePaged(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.admin()
+
+	account := api.must(http.StatusCreated, "POST", "/api/admin/service-accounts", adminToken, gin.H{"name": "SIS"})
+	created := api.must(http.StatusCreated, "POST", fmt.Sprintf("/api/admin/service-accounts/%v/keys", account["id"]), adminToken,
+		gin.H{"name": "sync", "scopes": []string{models.ScopeCoursesRead, models.ScopeEnrollmentsRead, models.ScopeGradesRead}})
+	key := created["key"].(string)
+
+	teacher := models.User{Name: "Teacher", Email: "teacher@university.edu", Password: "unused", Role: models.RoleTeacher}
+	config.DB.Create(&teacher)
+	var courseID uint
+	for i := 0; i < 3; i++ {
+		course := models.Course{Name: fmt.Sprintf("Course %d", i), TeacherID: teacher.ID}
+		config.DB.Create(&course)
+		courseID = course.ID
+	}
+	for i := 0; i < 3; i++ {
+		student := models.User{Name: "Student", Email: fmt.Sprintf("student%d@university.edu", i), Password: "unused", Role: models.RoleStudent}
+		config.DB.Create(&student)
+		config.DB.Create(&models.Enrollment{StudentID: student.ID, CourseID: courseID})
+		config.DB.Create(&models.Grade{StudentID: student.ID, CourseID: courseID, Marks: 90, GradeLetter: "A"})
+	}
+
+	for _, path := range []string{
+		"/api/integrations/courses",
+		fmt.Sprintf("/api/integrations/courses/%d/enrollments", courseID),
+		fmt.Sprintf("/api/integrations/courses/%d/grades", courseID),
+	} {
+		items, page := api.list(path, key)
+		if len(items) != 3 || page.Total != 3 || page.Limit != utils.MaxPageSize {
+			t.Errorf("GET %s: %d items, total %d, limit %d; want all 3 in a page of %d", path, len(items), page.Total, page.Limit, utils.MaxPageSize)
+		}
+		if strings.HasSuffix(path, "/enrollments") {
+			if student, _ := items[0].(map[string]interface{})["student"].(map[string]interface{}); student["email"] == "" || student["email"] == nil {
+				t.Errorf("GET %s: enrollments are listed without their students", path)
+			}
+		}
+
+		items, page = api.list(path+"?limit=2", key)
+		if len(items) != 2 || page.Next == "" {
+			t.Errorf("GET %s?limit=2: %d items, next %q; want 2 and a next link", path, len(items), page.Next)
+		}
+
+		for _, query := range []string{"limit=-1", "limit=abc", "limit=1000", "offset=-1"} {
+			if status, body := api.download(path+"?"+query, key); status != http.StatusBadRequest {
+				t.Errorf("GET %s?%s: got %d %s, want 400", path, query, status, body)
+			}
+		}
+	}
+}
+
+func TestDeleteRoleReportsCountFailures(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.admin()
+
+	role := api.must(http.StatusCreated, "POST", "/api/admin/roles", adminToken,
+		gin.H{"name": "registrar", "permissions": []string{models.PermCourseRead}})
+	registrar := models.User{Name: "Registrar", Email: "registrar@university.edu", Password: "unused", Role: "registrar"}
+	config.DB.Create(&registrar)
+
+	// Counting the role's users fails, while loading users for authentication still works
+	err := config.DB.Callback().Query().Before("gorm:query").Register("test:fail_user_count", func(db *gorm.DB) {
+		if _, counting := db.Statement.Dest.(*int64); counting && db.Statement.Table == "users" {
+			db.AddError(errors.New("connection lost"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api.must(http.StatusInternalServerError, "DELETE", fmt.Sprintf("/api/admin/roles/%v", role["id"]), adminToken, nil)
+	var roles int64
+	config.DB.Model(&models.Role{}).Where("name = ?", "registrar").Count(&roles)
+	if roles != 1 {
+		t.Error("the role was deleted although its users could not be counted")
+	}
+}
+
+// TestUploadGradesMatchesEmailsIgnoringCase uploads rows for a student whose
+// stored email has capitals, matched by email alone and with their ID
+func TestUploadGradesMatchesEmailsIgnoringCase(t *testing.T) {
+	api := newTestAPI(t)
+	hash, err := utils.HashPassword("teacher-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	teacher := models.User{Name: "Teacher", Email: "teacher@university.edu", Password: hash, Role: models.RoleTeacher}
+	alice := models.User{Name: "Alice", Email: "Alice@University.edu", Password: "unused", Role: models.RoleStudent}
+	bob := models.User{Name: "Bob", Email: "Bob@University.edu", Password: "unused", Role: models.RoleStudent}
+	config.DB.Create(&teacher)
+	config.DB.Create(&alice)
+	config.DB.Create(&bob)
+	course := models.Course{Name: "Algorithms", Term: "2026-Spring", TeacherID: teacher.ID}
+	config.DB.Create(&course)
+	config.DB.Create(&models.Enrollment{StudentID: alice.ID, CourseID: course.ID})
+	config.DB.Create(&models.Enrollment{StudentID: bob.ID, CourseID: course.ID})
+	token := api.login("teacher@university.edu", "teacher-password")
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, _ := form.CreateFormFile("file", "grades.csv")
+	fmt.Fprintf(file, "student_id,email,marks\n,alice@university.edu,91\n%d,BOB@university.edu,78\n", bob.ID)
+	form.Close()
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/teacher/courses/%d/grades/upload?dry_run=true", api.URL, course.ID), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var preview struct {
+		Data struct {
+			Rows []controllers.GradeUploadRow `json:"rows"`
+		} `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&preview)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload: got %d %+v, want 200", resp.StatusCode, preview.Data.Rows)
+	}
+	for i, want := range []uint{alice.ID, bob.ID} {
+		if row := preview.Data.Rows[i]; row.StudentID != want || row.Change != "new" {
+			t.Errorf("row %d: got student %d, change %q, errors %v; want student %d, new", row.Row, row.StudentID, row.Change, row.Errors, want)
+		}
+	}
+}
+
+// TestScoreAssistantsReadComponents checks that an assistant who may only
+// enter scores can list the components and scores they enter them against
+func TestScoreAssistantsReadComponents(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.admin()
+
+	teacher := api.must(http.StatusCreated, "POST", "/api/admin/users", adminToken,
+		gin.H{"name": "Anjali Desai", "email": "anjali@university.edu", "password": "teacher-password", "role": models.RoleTeacher})
+	assistant := api.must(http.StatusCreated, "POST", "/api/admin/users", adminToken,
+		gin.H{"name": "Vikram Rao", "email": "vikram@university.edu", "password": "assistant-password", "role": models.RoleTeacher})
+	course := api.must(http.StatusCreated, "POST", "/api/admin/courses", adminToken,
+		gin.H{"name": "Algorithms", "term": "2026-Spring", "teacher_id": teacher["id"]})
+	teacherToken := api.login("anjali@university.edu", "teacher-password")
+
+	coursePath := fmt.Sprintf("/api/teacher/courses/%v", course["id"])
+	component := api.must(http.StatusCreated, "POST", coursePath+"/components", teacherToken,
+		gin.H{"name": "Midterm", "weight": 40, "max_score": 50})
+	api.must(http.StatusOK, "POST", coursePath+"/assistants", teacherToken,
+		gin.H{"user_id": assistant["id"], "can_enter_scores": true})
+	assistantToken := api.login("vikram@university.edu", "assistant-password")
+
+	for path, want := range map[string]int{
+		coursePath + "/components": http.StatusOK,
+		fmt.Sprintf("%s/components/%v/scores", coursePath, component["id"]): http.StatusOK,
+		coursePath + "/roster": http.StatusForbidden,
+	} {
+		if status, body := api.download(path, assistantToken); status != want {
+			t.Errorf("GET %s: got %d %s, want %d", path, status, body, want)
+		}
+	}
+}
+
+// TestImpersonateRefusesAdmins checks that no session can be started as a
+// user holding admin permissions, whichever role grants them
+func TestImpersonateRefusesAdmins(t *testing.T) {
+	api := newTestAPI(t)
+	adminToken := api.admin()
+
+	newUser := func(email, role string) interface{} {
+		user := api.must(http.StatusCreated, "POST", "/api/admin/users", adminToken,
+			gin.H{"name": email, "email": email, "password": "user-password", "role": role})
+		return user["id"]
+	}
+	otherAdmin := models.User{Name: "Other Admin", Email: "other-admin@university.edu", Password: "unused", Role: models.RoleAdmin}
+	config.DB.Create(&otherAdmin)
+	teacher := newUser("teacher@university.edu", models.RoleTeacher)
+	userManager := newUser("manager@university.edu", models.RoleTeacher)
+	student := newUser("student@university.edu", models.RoleStudent)
+
+	api.must(http.StatusCreated, "POST", "/api/admin/roles", adminToken,
+		gin.H{"name": "user-manager", "permissions": []string{models.PermUserManage}})
+	api.must(http.StatusOK, "PUT", fmt.Sprintf("/api/admin/users/%v/roles", userManager), adminToken,
+		gin.H{"roles": []string{"user-manager"}})
+	var self models.User
+	config.DB.Where("email = ?", "admin@university.edu").First(&self)
+
+	tests := []struct {
+		name   string
+		target interface{}
+		want   int
+	}{
+		{"admin", otherAdmin.ID, http.StatusForbidden},
+		{"admin permission from a custom role", userManager, http.StatusForbidden},
+		{"self", self.ID, http.StatusBadRequest},
+		{"missing user", 999, http.StatusNotFound},
+		{"teacher", teacher, http.StatusOK},
+		{"student", student, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, data := api.call("POST", "/api/admin/impersonate", adminToken, gin.H{"user_id": tt.target, "reason": "support ticket"})
+			if status != tt.want {
+				t.Fatalf("impersonating %v: got %d %v, want %d", tt.target, status, data, tt.want)
+			}
+			if status == http.StatusOK && data["read_only"] != true {
+				t.Errorf("session is not read-only by default: %v", data)
+			}
+		})
+	}
+}
+
+// TestCourseStatisticsAndTemplate reads a small graded course through the
+// statistics, the GPA distribution and the grade upload template
+func TestCourseStatisticsAndTemplate(t *testing.T) {
+	api := newTestAPI(t)
+	hash, err := utils.HashPassword("teacher-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	teacher := models.User{Name: "Teacher", Email: "teacher@university.edu", Password: hash, Role: models.RoleTeacher}
+	config.DB.Create(&teacher)
+	course := models.Course{Name: "Algorithms", Term: "2026-Spring", TeacherID: teacher.ID}
+	config.DB.Create(&course)
+	component := models.AssessmentComponent{CourseID: course.ID, Name: "Midterm", Weight: 40, MaxScore: 50}
+	config.DB.Create(&component)
+
+	// Five graded students, one ungraded and one deleted after enrolling
+	for i, marks := range []float64{55, 70, 82, 90, 100, -1, -2} {
+		student := models.User{Name: fmt.Sprintf("Student %d", i), Email: fmt.Sprintf("student%d@university.edu", i), Password: "unused", Role: models.RoleStudent}
+		config.DB.Create(&student)
+		config.DB.Create(&models.Enrollment{StudentID: student.ID, CourseID: course.ID})
+		switch {
+		case marks >= 0:
+			config.DB.Create(&models.Grade{StudentID: student.ID, CourseID: course.ID, Marks: marks, GradeLetter: services.GradeLetter(marks)})
+			config.DB.Create(&models.ComponentScore{ComponentID: component.ID, StudentID: student.ID, Score: marks / 2, GradedByID: teacher.ID})
+		case marks == -2:
+			config.DB.Delete(&student)
+		}
+	}
+	token := api.login("teacher@university.edu", "teacher-password")
+
+	status, body := api.download(fmt.Sprintf("/api/teacher/courses/%d/stats?bins=4", course.ID), token)
+	if status != http.StatusOK {
+		t.Fatalf("stats: got %d %s", status, body)
+	}
+	var stats struct {
+		Data struct {
+			Enrolled     int64 `json:"enrolled"`
+			GradeLetters []struct {
+				GradeLetter string `json:"grade_letter"`
+				Count       int    `json:"count"`
+			} `json:"grade_letters"`
+			Marks      services.MarkStatistics        `json:"marks"`
+			Components []services.ComponentStatistics `json:"components"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &stats); err != nil {
+		t.Fatal(err)
+	}
+	data := stats.Data
+	if data.Enrolled != 7 || len(data.GradeLetters) != 4 || data.GradeLetters[0].GradeLetter != "A" || data.GradeLetters[0].Count != 2 {
+		t.Errorf("enrolled %d, letters %+v", data.Enrolled, data.GradeLetters)
+	}
+	marks := data.Marks
+	if marks.Graded != 5 || marks.Ungraded != 2 || *marks.Mean != 79.4 || *marks.StdDev != 15.67 || *marks.Min != 55 || *marks.Max != 100 ||
+		*marks.Q1 != 70 || *marks.Median != 82 || *marks.Q3 != 90 || *marks.PassRate != 80 {
+		t.Errorf("marks: %s", body)
+	}
+	if counts := fmt.Sprint(marks.Histogram); counts != "[{0 25 0} {25 50 0} {50 75 2} {75 100 3}]" {
+		t.Errorf("histogram %s", counts)
+	}
+	if len(data.Components) != 1 {
+		t.Fatalf("components: %+v", data.Components)
+	}
+	scores := data.Components[0].Statistics
+	if scores.Graded != 5 || *scores.Mean != 39.7 || *scores.Median != 41 || *scores.PassRate != 80 || fmt.Sprint(scores.Histogram) != "[{0 12.5 0} {12.5 25 0} {25 37.5 2} {37.5 50 3}]" {
+		t.Errorf("component statistics: %+v", scores)
+	}
+
+	// The GPA distribution aggregates the same way over grade points
+	gpa := api.must(http.StatusOK, "GET", "/api/admin/analytics/gpa", api.admin(), nil)
+	if gpa["graded"] != 5.0 || gpa["ungraded"] != 1.0 || gpa["mean"] != 2.6 || gpa["median"] != 3.0 || gpa["pass_rate"] != nil {
+		t.Errorf("GPA distribution: %v", gpa)
+	}
+
+	status, body = api.download(fmt.Sprintf("/api/teacher/courses/%d/grades/template", course.ID), token)
+	if status != http.StatusOK {
+		t.Fatalf("template: got %d %s", status, body)
+	}
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) != 7 || !strings.Contains(lines[1], "student0@university.edu,Student 0,55") || !strings.HasSuffix(lines[6], "Student 5,") {
+		t.Errorf("template:\n%s", body)
+	}
+}
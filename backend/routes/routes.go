@@ -2,50 +2,151 @@ package routes
 
 import (
 	"grade-management-system/controllers"
+	"grade-management-system/docs"
+	"grade-management-system/metrics"
 	"grade-management-system/middleware"
+	"grade-management-system/models"
+	"grade-management-system/tracing"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes() *gin.Engine {
-	r := gin.Default()
+// SetupRoutes registers every endpoint. h serves the endpoints backed by the domain services.
+func SetupRoutes(h *controllers.Handlers) *gin.Engine {
+	r := gin.New()
+	if tracing.Enabled() {
+		r.Use(middleware.Tracing())
+	}
+	r.Use(middleware.RequestID(), middleware.RequestLogger(), middleware.Metrics(), middleware.Recovery())
 
 	// Public routes
 	r.GET("/health", controllers.HealthCheck)
+	r.GET("/metrics", middleware.MetricsTokenRequired(), gin.WrapH(metrics.Handler()))
+	r.GET("/openapi.json", docs.Spec)
+	r.GET("/docs", docs.Page)
 	r.POST("/register", controllers.RegisterStudent)
 	r.POST("/login", controllers.Login)
+	r.GET("/.well-known/jwks.json", controllers.JWKS)
+	r.GET("/auth/oidc/login", controllers.OIDCLogin)
+	r.GET("/auth/oidc/callback", controllers.OIDCCallback)
 
 	// Protected routes
 	api := r.Group("/api")
 	api.Use(middleware.AuthRequired())
 
+	// Routes for any logged-in user
+	api.POST("/me/password", controllers.ChangePassword) // Must match middleware.ChangePasswordPath
+
 	// Admin routes
 	admin := api.Group("/admin")
-	admin.Use(middleware.RoleRequired("admin"))
 	{
-		admin.POST("/users", controllers.CreateUser)
-		admin.POST("/courses", controllers.CreateCourse)
-		admin.GET("/students", controllers.ListStudents)
-		admin.GET("/courses", controllers.ListCourses)
+		admin.GET("/users", middleware.PermissionRequired(models.PermUserManage), controllers.ListUsers)
+		admin.POST("/users", middleware.PermissionRequired(models.PermUserCreate), h.CreateUser)
+		admin.POST("/users/import", middleware.PermissionRequired(models.PermUserCreate), controllers.ImportUsers)
+		admin.PUT("/users/:id", middleware.PermissionRequired(models.PermUserManage), h.UpdateUser)
+		admin.POST("/users/:id/deactivate", middleware.PermissionRequired(models.PermUserManage), h.DeactivateUser)
+		admin.POST("/users/:id/reactivate", middleware.PermissionRequired(models.PermUserManage), h.ReactivateUser)
+		admin.DELETE("/users/:id", middleware.PermissionRequired(models.PermUserManage), h.DeleteUser)
+		admin.POST("/courses", middleware.PermissionRequired(models.PermCourseCreate), h.CreateCourse)
+		admin.PUT("/courses/:id", middleware.PermissionRequired(models.PermCourseManage), h.UpdateCourse)
+		admin.PUT("/courses/:id/teacher", middleware.PermissionRequired(models.PermCourseManage), h.ReassignTeacher)
+		admin.POST("/courses/:id/archive", middleware.PermissionRequired(models.PermCourseManage), h.ArchiveCourse)
+		admin.POST("/courses/:id/unarchive", middleware.PermissionRequired(models.PermCourseManage), h.UnarchiveCourse)
+		admin.POST("/courses/:id/enrollments/bulk", middleware.PermissionRequired(models.PermEnrollmentManage), h.AdminBulkEnroll)
+		admin.DELETE("/courses/:id", middleware.PermissionRequired(models.PermCourseManage), h.DeleteCourse)
+		admin.GET("/students", middleware.PermissionRequired(models.PermStudentRead), controllers.ListStudents)
+		admin.GET("/students/:id/export", middleware.PermissionRequired(models.PermGradeExport), controllers.ExportStudentRecord)
+		admin.GET("/exports/grades", middleware.PermissionRequired(models.PermGradeExport), controllers.ExportInstitutionGrades)
+		admin.GET("/courses", middleware.PermissionRequired(models.PermCourseRead), controllers.ListCourses)
+		admin.POST("/service-accounts", middleware.PermissionRequired(models.PermServiceAccountManage), controllers.CreateServiceAccount)
+		admin.GET("/service-accounts", middleware.PermissionRequired(models.PermServiceAccountManage), controllers.ListServiceAccounts)
+		admin.POST("/service-accounts/:id/keys", middleware.PermissionRequired(models.PermServiceAccountManage), controllers.CreateAPIKey)
+		admin.DELETE("/api-keys/:keyId", middleware.PermissionRequired(models.PermServiceAccountManage), controllers.RevokeAPIKey)
+		admin.POST("/impersonate", middleware.PermissionRequired(models.PermUserImpersonate), controllers.Impersonate)
+		admin.GET("/analytics/enrollments", middleware.PermissionRequired(models.PermAnalyticsRead), controllers.EnrollmentAnalytics)
+		admin.GET("/analytics/grades", middleware.PermissionRequired(models.PermAnalyticsRead), controllers.GradeDistributionAnalytics)
+		admin.GET("/analytics/gpa", middleware.PermissionRequired(models.PermAnalyticsRead), controllers.GPADistributionAnalytics)
+		admin.GET("/analytics/outstanding-grading", middleware.PermissionRequired(models.PermAnalyticsRead), controllers.OutstandingGradingAnalytics)
+		admin.GET("/analytics/trends", middleware.PermissionRequired(models.PermAnalyticsRead), controllers.TrendAnalytics)
+		admin.GET("/at-risk", middleware.PermissionRequired(models.PermRiskRead), controllers.AdminListAtRisk)
+		admin.GET("/risk-rules", middleware.PermissionRequired(models.PermRiskManage), controllers.ListRiskRules)
+		admin.PUT("/risk-rules/:id", middleware.PermissionRequired(models.PermRiskManage), controllers.UpdateRiskRule)
+		admin.POST("/risk-rules/evaluate", middleware.PermissionRequired(models.PermRiskManage), controllers.EvaluateRiskRules)
+		admin.GET("/audit-logs", middleware.PermissionRequired(models.PermAuditRead), controllers.ListAuditLogs)
+		admin.GET("/permissions", middleware.PermissionRequired(models.PermRoleManage), controllers.ListPermissions)
+		admin.GET("/roles", middleware.PermissionRequired(models.PermRoleManage), controllers.ListRoles)
+		admin.POST("/roles", middleware.PermissionRequired(models.PermRoleManage), controllers.CreateRole)
+		admin.PUT("/roles/:id", middleware.PermissionRequired(models.PermRoleManage), controllers.UpdateRole)
+		admin.DELETE("/roles/:id", middleware.PermissionRequired(models.PermRoleManage), controllers.DeleteRole)
+		admin.PUT("/users/:id/roles", middleware.PermissionRequired(models.PermRoleManage), controllers.AssignUserRoles)
+		admin.PUT("/users/:id/guardian-settings", middleware.PermissionRequired(models.PermGuardianManage), controllers.UpdateGuardianSettings)
+		admin.GET("/guardian-links", middleware.PermissionRequired(models.PermGuardianManage), controllers.ListGuardianLinks)
+		admin.POST("/guardian-links", middleware.PermissionRequired(models.PermGuardianManage), controllers.CreateGuardianLink)
+		admin.DELETE("/guardian-links/:id", middleware.PermissionRequired(models.PermGuardianManage), controllers.RevokeGuardianLink)
 	}
 
 	// Teacher routes
 	teacher := api.Group("/teacher")
-	teacher.Use(middleware.RoleRequired("teacher"))
 	{
-		teacher.GET("/courses", controllers.GetAssignedCourses)
-		teacher.POST("/enrollments", controllers.EnrollStudent)
-		teacher.POST("/grades", controllers.AddOrUpdateGrade)
-		teacher.GET("/courses/:courseId/stats", controllers.GetGradeStatistics)
+		teacher.GET("/courses", middleware.PermissionRequired(models.PermCourseTeach), controllers.GetAssignedCourses)
+		teacher.POST("/enrollments", middleware.PermissionRequired(models.PermEnrollmentWrite), h.EnrollStudent)
+		teacher.POST("/courses/:courseId/enrollments/bulk", middleware.PermissionRequired(models.PermEnrollmentWrite), h.TeacherBulkEnroll)
+		teacher.POST("/grades", middleware.PermissionRequired(models.PermGradeWrite), h.AddOrUpdateGrade)
+		teacher.GET("/courses/:courseId/gradebook/export", middleware.PermissionRequired(models.PermCourseTeach), controllers.ExportCourseGradebook)
+		teacher.GET("/courses/:courseId/grades/template", middleware.PermissionRequired(models.PermGradeWrite), h.DownloadGradeTemplate)
+		teacher.POST("/courses/:courseId/grades/upload", middleware.PermissionRequired(models.PermGradeWrite), h.UploadGrades)
+		teacher.PUT("/courses/:courseId/settings", middleware.PermissionRequired(models.PermCourseTeach), controllers.UpdateCourseSettings)
+		teacher.GET("/at-risk", middleware.PermissionRequired(models.PermCourseTeach), controllers.TeacherListAtRisk)
+		teacher.GET("/courses/:courseId/stats", middleware.PermissionRequired(models.PermGradeStatsRead), h.GetGradeStatistics)
+		teacher.GET("/courses/:courseId/grade-history", middleware.PermissionRequired(models.PermCourseTeach), controllers.GetGradeHistory)
+		teacher.GET("/courses/:courseId/assistants", middleware.PermissionRequired(models.PermCourseTeach), controllers.ListAssistants)
+		teacher.POST("/courses/:courseId/assistants", middleware.PermissionRequired(models.PermCourseTeach), controllers.AddOrUpdateAssistant)
+		teacher.DELETE("/courses/:courseId/assistants/:userId", middleware.PermissionRequired(models.PermCourseTeach), controllers.RemoveAssistant)
+		teacher.POST("/courses/:courseId/components", middleware.PermissionRequired(models.PermCourseTeach), controllers.CreateComponent)
+		teacher.GET("/courses/:courseId/attendance", middleware.PermissionRequired(models.PermAttendanceWrite), controllers.ListCourseAttendance)
+		teacher.POST("/courses/:courseId/attendance", middleware.PermissionRequired(models.PermAttendanceWrite), controllers.RecordAttendance)
+
+		// Also reachable by teaching assistants, subject to the abilities delegated in each course
+		teacher.GET("/assisted-courses", middleware.PermissionRequired(models.PermCourseAssist), controllers.GetAssistedCourses)
+		teacher.GET("/courses/:courseId/roster", middleware.PermissionRequired(models.PermCourseTeach, models.PermCourseAssist), controllers.GetCourseRoster)
+		teacher.GET("/courses/:courseId/components", middleware.PermissionRequired(models.PermCourseTeach, models.PermCourseAssist), controllers.ListComponents)
+		teacher.GET("/courses/:courseId/components/:componentId/scores", middleware.PermissionRequired(models.PermCourseTeach, models.PermCourseAssist), controllers.ListComponentScores)
+		teacher.POST("/courses/:courseId/components/:componentId/scores", middleware.PermissionRequired(models.PermCourseTeach, models.PermCourseAssist), h.AddOrUpdateComponentScore)
 	}
 
 	// Student routes
 	student := api.Group("/student")
-	student.Use(middleware.RoleRequired("student"))
 	{
-		student.GET("/courses", controllers.GetStudentCourses)
-		student.GET("/grades", controllers.GetStudentGrades)
-		student.GET("/gpa", controllers.GetStudentGPA)
+		student.GET("/courses", middleware.PermissionRequired(models.PermOwnCoursesRead), h.GetStudentCourses)
+		student.GET("/grades", middleware.PermissionRequired(models.PermOwnGradesRead), h.GetStudentGrades)
+		student.GET("/gpa", middleware.PermissionRequired(models.PermOwnGradesRead), h.GetStudentGPA)
+		student.GET("/record/export", middleware.PermissionRequired(models.PermOwnGradesRead), controllers.ExportMyRecord)
+		student.GET("/attendance", middleware.PermissionRequired(models.PermOwnAttendanceRead), controllers.GetStudentAttendance)
+		student.GET("/guardians", middleware.PermissionRequired(models.PermOwnGradesRead), controllers.GetMyGuardians)
+		student.POST("/guardians/:linkId/approve", middleware.PermissionRequired(models.PermOwnGradesRead), controllers.ApproveGuardian)
+		student.POST("/guardians/:linkId/revoke", middleware.PermissionRequired(models.PermOwnGradesRead), controllers.RevokeGuardian)
+	}
+
+	// Guardian routes (read-only access to linked students)
+	guardian := api.Group("/guardian")
+	guardian.Use(middleware.PermissionRequired(models.PermGuardianRead))
+	{
+		guardian.GET("/students", controllers.GetLinkedStudents)
+		guardian.POST("/invitations", controllers.InviteStudent)
+		guardian.GET("/students/:studentId/courses", h.GetLinkedStudentCourses)
+		guardian.GET("/students/:studentId/grades", h.GetLinkedStudentGrades)
+		guardian.GET("/students/:studentId/gpa", h.GetLinkedStudentGPA)
+		guardian.GET("/students/:studentId/attendance", controllers.GetLinkedStudentAttendance)
+	}
+
+	// Integration routes for service accounts (API keys only)
+	integrations := api.Group("/integrations")
+	{
+		integrations.GET("/courses", middleware.ScopeRequired(models.ScopeCoursesRead), controllers.IntegrationListCourses)
+		integrations.GET("/courses/:courseId/enrollments", middleware.ScopeRequired(models.ScopeEnrollmentsRead), controllers.IntegrationListEnrollments)
+		integrations.POST("/enrollments", middleware.ScopeRequired(models.ScopeEnrollmentsWrite), h.IntegrationEnrollStudent)
+		integrations.GET("/courses/:courseId/grades", middleware.ScopeRequired(models.ScopeGradesRead), controllers.IntegrationListGrades)
+		integrations.POST("/grades", middleware.ScopeRequired(models.ScopeGradesWrite), h.IntegrationAddOrUpdateGrade)
 	}
 
 	return r
@@ -0,0 +1,135 @@
+package routes
+
+import (
+	"encoding/json"
+	"grade-management-system/controllers"
+	"grade-management-system/docs"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPISchema struct {
+	Required   []string                   `json:"required"`
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+func loadSpec(t *testing.T) openAPIDocument {
+	t.Helper()
+	var spec openAPIDocument
+	if err := json.Unmarshal(docs.OpenAPI(), &spec); err != nil {
+		t.Fatalf("docs/openapi.json is not valid JSON: %v", err)
+	}
+	return spec
+}
+
+// registeredOperations returns every route as "METHOD /path/{param}"
+func registeredOperations() map[string]bool {
+	gin.SetMode(gin.TestMode)
+	r := SetupRoutes(controllers.NewHandlers(nil, nil, nil, nil))
+
+	ops := map[string]bool{}
+	for _, route := range r.Routes() {
+		ops[route.Method+" "+pathParam.ReplaceAllString(route.Path, "{$1}")] = true
+	}
+	return ops
+}
+
+func TestSpecDocumentsEveryRoute(t *testing.T) {
+	spec := loadSpec(t)
+	documented := map[string]bool{}
+	for path, item := range spec.Paths {
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	registered := registeredOperations()
+
+	var missing, stale []string
+	for op := range registered {
+		if !documented[op] {
+			missing = append(missing, op)
+		}
+	}
+	for op := range documented {
+		if !registered[op] {
+			stale = append(stale, op)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	for _, op := range missing {
+		t.Errorf("route %s is not in docs/openapi.json", op)
+	}
+	for _, op := range stale {
+		t.Errorf("docs/openapi.json documents %s, which is not a registered route", op)
+	}
+}
+
+// TestSpecMatchesInputTypes checks the request schemas against the json and
+// binding tags of the types the handlers bind
+func TestSpecMatchesInputTypes(t *testing.T) {
+	inputs := []any{
+		controllers.RegisterInput{}, controllers.LoginInput{}, controllers.ChangePasswordInput{},
+		controllers.CreateUserInput{}, controllers.UpdateUserInput{}, controllers.CreateCourseInput{},
+		controllers.UpdateCourseInput{}, controllers.ReassignTeacherInput{}, controllers.BulkEnrollInput{},
+		controllers.CohortInput{}, controllers.EnrollStudentInput{}, controllers.GradeInput{},
+		controllers.CourseSettingsInput{}, controllers.AssistantInput{}, controllers.ComponentInput{},
+		controllers.ComponentScoreInput{}, controllers.AttendanceInput{}, controllers.CreateServiceAccountInput{},
+		controllers.CreateAPIKeyInput{}, controllers.ImpersonateInput{}, controllers.UpdateRiskRuleInput{},
+		controllers.RoleInput{}, controllers.UpdateRoleInput{}, controllers.AssignRolesInput{},
+		controllers.GuardianLinkInput{}, controllers.GuardianSettingsInput{}, controllers.GuardianInviteInput{},
+	}
+
+	spec := loadSpec(t)
+	for _, input := range inputs {
+		typ := reflect.TypeOf(input)
+		schema, ok := spec.Components.Schemas[typ.Name()]
+		if !ok {
+			t.Errorf("docs/openapi.json has no schema for %s", typ.Name())
+			continue
+		}
+
+		var fields, required []string
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			fields = append(fields, name)
+			if slices.Contains(strings.Split(field.Tag.Get("binding"), ","), "required") {
+				required = append(required, name)
+			}
+		}
+
+		var properties []string
+		for name := range schema.Properties {
+			properties = append(properties, name)
+		}
+		sort.Strings(fields)
+		sort.Strings(properties)
+		sort.Strings(required)
+		sort.Strings(schema.Required)
+		if !slices.Equal(fields, properties) {
+			t.Errorf("schema %s has properties %v, the Go type has %v", typ.Name(), properties, fields)
+		}
+		if !slices.Equal(required, schema.Required) {
+			t.Errorf("schema %s requires %v, the Go type requires %v", typ.Name(), schema.Required, required)
+		}
+	}
+}
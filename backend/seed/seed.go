@@ -1,9 +1,10 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 
 	"grade-management-system/config"
+	"grade-management-system/logging"
 	"grade-management-system/models"
 	"grade-management-system/utils"
 
@@ -12,9 +13,10 @@ import (
 
 func main() {
 	_ = godotenv.Load()
+	logging.Setup()
 	config.ConnectDatabase()
 
-	log.Println("Starting to seed database...")
+	slog.Info("Starting to seed database")
 
 	// 1. Seed Admin
 	adminPassword, _ := utils.HashPassword("admin123")
@@ -25,7 +27,7 @@ func main() {
 		Role:     "admin",
 	}
 	if err := config.DB.Where("email = ?", admin.Email).FirstOrCreate(&admin).Error; err != nil {
-		log.Fatal("Failed to seed admin:", err)
+		logging.Fatal("Failed to seed admin", "error", err)
 	}
 
 	// 2. Seed Teacher
@@ -50,8 +52,10 @@ func main() {
 
 	// 4. Seed Course
 	course := models.Course{
-		Name:      "Data Structures and Algorithms",
-		TeacherID: teacher.ID,
+		Name:       "Data Structures and Algorithms",
+		Term:       "2025-Fall",
+		Department: "CSE",
+		TeacherID:  teacher.ID,
 	}
 	config.DB.Where("name = ?", course.Name).FirstOrCreate(&course)
 
@@ -71,8 +75,7 @@ func main() {
 	}
 	config.DB.Where("student_id = ? AND course_id = ?", student.ID, course.ID).FirstOrCreate(&grade)
 
-	log.Println("Database seeded successfully! You can now log in.")
-	log.Println("Admin: hod.cse@university.edu.in / admin123")
-	log.Println("Teacher: anjali.desai@university.edu.in / teacher123")
-	log.Println("Student: rahul.verma@student.edu.in / student123")
+	// The demo passwords are listed in the README rather than logged
+	slog.Info("Database seeded successfully, you can now log in",
+		"admin", admin.Email, "teacher", teacher.Email, "student", student.Email)
 }
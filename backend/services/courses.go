@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"grade-management-system/models"
+	"grade-management-system/repository"
+	"time"
+)
+
+// CourseUpdate holds course detail changes; nil fields are left as they are
+type CourseUpdate struct {
+	Name        *string
+	Description *string
+	Term        *string
+	Department  *string
+}
+
+// CourseService manages courses and who teaches them
+type CourseService interface {
+	Find(ctx context.Context, id uint) (models.Course, error)
+	// FindTaught returns the course only if teacherID teaches it
+	FindTaught(ctx context.Context, id, teacherID uint) (models.Course, error)
+	// Create adds a course taught by an active teacher
+	Create(ctx context.Context, course *models.Course) error
+	// Update applies the changes and returns the names of the fields changed
+	Update(ctx context.Context, course *models.Course, update CourseUpdate) ([]string, error)
+	// Reassign moves the course to another active teacher, dropping them as an
+	// assistant of the course, and reports whether anything changed
+	Reassign(ctx context.Context, course *models.Course, teacherID uint) (bool, error)
+	// Archive closes the course to new enrollments and reports whether anything changed
+	Archive(ctx context.Context, course *models.Course) (bool, error)
+	// Unarchive reopens the course and reports whether anything changed
+	Unarchive(ctx context.Context, course *models.Course) (bool, error)
+	// Delete removes a course nobody has marks in
+	Delete(ctx context.Context, course *models.Course) error
+}
+
+// NewCourseService returns a CourseService
+func NewCourseService(store repository.Store) CourseService {
+	return &courseService{store: store}
+}
+
+type courseService struct {
+	store repository.Store
+}
+
+func (s *courseService) Find(ctx context.Context, id uint) (models.Course, error) {
+	course, err := s.store.Courses().FindByID(ctx, id)
+	return course, orNotFound(err, "Course not found")
+}
+
+func (s *courseService) FindTaught(ctx context.Context, id, teacherID uint) (models.Course, error) {
+	course, err := s.store.Courses().FindByID(ctx, id)
+	if err == nil && course.TeacherID != teacherID {
+		err = repository.ErrNotFound
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return course, forbidden("Course not found or you don't have access")
+	}
+	return course, err
+}
+
+func (s *courseService) Create(ctx context.Context, course *models.Course) error {
+	if err := s.checkTeacher(ctx, course.TeacherID); err != nil {
+		return err
+	}
+	if err := s.store.Courses().Create(ctx, course); err != nil {
+		return invalid("Failed to create course. Ensure course name is unique.")
+	}
+	return nil
+}
+
+func (s *courseService) Update(ctx context.Context, course *models.Course, update CourseUpdate) ([]string, error) {
+	var changes []string
+	if update.Name != nil && *update.Name != course.Name {
+		course.Name = *update.Name
+		changes = append(changes, "name")
+	}
+	if update.Description != nil && *update.Description != course.Description {
+		course.Description = *update.Description
+		changes = append(changes, "description")
+	}
+	if update.Term != nil && *update.Term != course.Term {
+		course.Term = *update.Term
+		changes = append(changes, "term")
+	}
+	if update.Department != nil && *update.Department != course.Department {
+		course.Department = *update.Department
+		changes = append(changes, "department")
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if err := s.store.Courses().Update(ctx, course, "Name", "Description", "Term", "Department"); err != nil {
+		return nil, invalid("Failed to update course. Ensure course name is unique.")
+	}
+	return changes, nil
+}
+
+func (s *courseService) Reassign(ctx context.Context, course *models.Course, teacherID uint) (bool, error) {
+	if teacherID == course.TeacherID {
+		return false, nil
+	}
+	if err := s.checkTeacher(ctx, teacherID); err != nil {
+		return false, err
+	}
+
+	err := s.store.Transaction(ctx, func(tx repository.Store) error {
+		course.TeacherID = teacherID
+		if err := tx.Courses().Update(ctx, course, "TeacherID"); err != nil {
+			return err
+		}
+		return tx.Courses().RemoveAssistant(ctx, course.ID, teacherID)
+	})
+	return err == nil, err
+}
+
+func (s *courseService) Archive(ctx context.Context, course *models.Course) (bool, error) {
+	if course.ArchivedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	course.ArchivedAt = &now
+	return true, s.store.Courses().Update(ctx, course, "ArchivedAt")
+}
+
+func (s *courseService) Unarchive(ctx context.Context, course *models.Course) (bool, error) {
+	if course.ArchivedAt == nil {
+		return false, nil
+	}
+
+	course.ArchivedAt = nil
+	return true, s.store.Courses().Update(ctx, course, "ArchivedAt")
+}
+
+func (s *courseService) Delete(ctx context.Context, course *models.Course) error {
+	hasMarks, err := s.store.Courses().HasMarks(ctx, course.ID)
+	if err != nil {
+		return err
+	}
+	if hasMarks {
+		return conflict("Course has grades and cannot be deleted; archive it instead")
+	}
+
+	// Enrollments, components, assistants and attendance cascade with the course
+	return s.store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.Grades().DeleteHistory(ctx, course.ID); err != nil {
+			return err
+		}
+		return tx.Courses().Delete(ctx, course)
+	})
+}
+
+// checkTeacher verifies the user exists, is an active teacher and can be assigned a course
+func (s *courseService) checkTeacher(ctx context.Context, teacherID uint) error {
+	teacher, err := s.store.Users().FindByID(ctx, teacherID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return invalid("Teacher not found")
+	}
+	if err != nil {
+		return err
+	}
+	if teacher.Role != models.RoleTeacher {
+		return invalid("Assigned user is not a teacher")
+	}
+	if teacher.DeactivatedAt != nil {
+		return invalid("Assigned teacher is deactivated")
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"grade-management-system/models"
+	"grade-management-system/repository"
+	"strconv"
+)
+
+// Outcomes of a student in a bulk enrollment
+const (
+	EnrollStatusEnrolled        = "enrolled"
+	EnrollStatusAlreadyEnrolled = "already_enrolled"
+	EnrollStatusNotAStudent     = "not_a_student"
+	EnrollStatusNotFound        = "not_found"
+	EnrollStatusDeactivated     = "deactivated"
+	EnrollStatusSkipped         = "skipped" // Eligible, but an all-or-nothing request failed
+)
+
+// BulkEnrollResult is the outcome of one student requested from EnrollMany
+type BulkEnrollResult struct {
+	Index   int          // Position of the student in the request
+	Student *models.User // Nil when no user matches
+	Status  string
+}
+
+// Failed reports whether the student could not be enrolled
+func (r BulkEnrollResult) Failed() bool {
+	return r.Status != EnrollStatusEnrolled && r.Status != EnrollStatusAlreadyEnrolled && r.Status != EnrollStatusSkipped
+}
+
+// EnrollmentService enrolls students in courses
+type EnrollmentService interface {
+	// Enroll enrolls an active student in a course that is not archived
+	Enroll(ctx context.Context, course models.Course, studentID uint) (models.Enrollment, error)
+	// EnrollMany enrolls students given by ID or lowercase email, checking each
+	// like Enroll. A student requested more than once is reported once. With
+	// allOrNothing, no one is enrolled unless every student can be.
+	EnrollMany(ctx context.Context, course models.Course, students []string, allOrNothing bool) ([]BulkEnrollResult, error)
+	// Cohort returns the IDs of the active students of a department and, when
+	// fromCourseID is not zero, enrolled in that course
+	Cohort(ctx context.Context, department string, fromCourseID uint) ([]uint, error)
+	// StudentCourses returns the courses the student is enrolled in
+	StudentCourses(ctx context.Context, studentID uint) ([]models.Course, error)
+}
+
+// NewEnrollmentService returns an EnrollmentService
+func NewEnrollmentService(store repository.Store) EnrollmentService {
+	return &enrollmentService{store: store}
+}
+
+type enrollmentService struct {
+	store repository.Store
+}
+
+func (s *enrollmentService) Enroll(ctx context.Context, course models.Course, studentID uint) (models.Enrollment, error) {
+	if course.ArchivedAt != nil {
+		return models.Enrollment{}, invalid("Course is archived and accepts no new enrollments")
+	}
+
+	student, err := s.store.Users().FindByID(ctx, studentID)
+	if err != nil {
+		return models.Enrollment{}, orNotFound(err, "Student not found")
+	}
+	if status := eligibility(student); status != "" {
+		return models.Enrollment{}, invalid("%s", ineligible[status])
+	}
+
+	enrollment := models.Enrollment{StudentID: studentID, CourseID: course.ID}
+	if err := s.store.Enrollments().Create(ctx, &enrollment); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return enrollment, invalid("Student is already enrolled in this course")
+		}
+		return enrollment, err
+	}
+	return enrollment, nil
+}
+
+// ineligible explains the statuses returned by eligibility
+var ineligible = map[string]string{
+	EnrollStatusNotAStudent: "User is not a student",
+	EnrollStatusDeactivated: "Student account is deactivated",
+}
+
+// eligibility returns why the user cannot be enrolled, or "" if they can
+func eligibility(user models.User) string {
+	switch {
+	case user.Role != models.RoleStudent:
+		return EnrollStatusNotAStudent
+	case user.DeactivatedAt != nil:
+		return EnrollStatusDeactivated
+	}
+	return ""
+}
+
+func (s *enrollmentService) EnrollMany(ctx context.Context, course models.Course, students []string, allOrNothing bool) ([]BulkEnrollResult, error) {
+	if course.ArchivedAt != nil {
+		return nil, invalid("Course is archived and accepts no new enrollments")
+	}
+
+	var ids []uint
+	var emails []string
+	for _, student := range students {
+		if id, err := strconv.ParseUint(student, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		} else if student != "" {
+			emails = append(emails, student)
+		}
+	}
+
+	users, err := s.store.Users().FindByIDsOrEmails(ctx, ids, emails)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[uint]*models.User{}
+	byEmail := map[string]*models.User{}
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+		byEmail[users[i].Email] = &users[i]
+	}
+
+	enrolledIDs, err := s.store.Enrollments().StudentIDs(ctx, course.ID)
+	if err != nil {
+		return nil, err
+	}
+	enrolled := map[uint]bool{}
+	for _, id := range enrolledIDs {
+		enrolled[id] = true
+	}
+
+	seen := map[uint]bool{}
+	results := make([]BulkEnrollResult, 0, len(students))
+	failed := false
+	for i, student := range students {
+		user := byEmail[student]
+		if id, err := strconv.ParseUint(student, 10, 64); err == nil {
+			user = byID[uint(id)]
+		}
+
+		result := BulkEnrollResult{Index: i, Student: user}
+		if user == nil {
+			result.Status = EnrollStatusNotFound
+			results = append(results, result)
+			failed = true
+			continue
+		}
+		if seen[user.ID] {
+			continue
+		}
+		seen[user.ID] = true
+
+		result.Status = eligibility(*user)
+		switch {
+		case result.Status != "":
+			failed = true
+		case enrolled[user.ID]:
+			result.Status = EnrollStatusAlreadyEnrolled
+		default:
+			result.Status = EnrollStatusEnrolled
+		}
+		results = append(results, result)
+	}
+
+	if allOrNothing && failed {
+		for i := range results {
+			if results[i].Status == EnrollStatusEnrolled {
+				results[i].Status = EnrollStatusSkipped
+			}
+		}
+		return results, nil
+	}
+
+	var studentIDs []uint
+	for _, result := range results {
+		if result.Status == EnrollStatusEnrolled {
+			studentIDs = append(studentIDs, result.Student.ID)
+		}
+	}
+	inserted, err := s.store.Enrollments().CreateMany(ctx, course.ID, studentIDs)
+	if err != nil {
+		return nil, err
+	}
+	// Students enrolled concurrently since the lookup were left as they are
+	for i := range results {
+		if results[i].Status == EnrollStatusEnrolled && !inserted[results[i].Student.ID] {
+			results[i].Status = EnrollStatusAlreadyEnrolled
+		}
+	}
+	return results, nil
+}
+
+func (s *enrollmentService) Cohort(ctx context.Context, department string, fromCourseID uint) ([]uint, error) {
+	if department == "" && fromCourseID == 0 {
+		return nil, invalid("A cohort needs a department or a from_course_id")
+	}
+	return s.store.Users().ActiveStudentIDs(ctx, department, fromCourseID)
+}
+
+func (s *enrollmentService) StudentCourses(ctx context.Context, studentID uint) ([]models.Course, error) {
+	return s.store.Enrollments().CoursesOf(ctx, studentID)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"grade-management-system/models"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEnroll(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	enrollments := NewEnrollmentService(f.store)
+
+	newcomer := f.addUser(t, "newcomer@university.edu", models.RoleStudent)
+	if _, err := enrollments.Enroll(ctx, f.course, newcomer.ID); err != nil {
+		t.Fatal(err)
+	}
+	if courses, _ := enrollments.StudentCourses(ctx, newcomer.ID); len(courses) != 1 || courses[0].ID != f.course.ID {
+		t.Errorf("newcomer is enrolled in %v, want only the course", courses)
+	}
+
+	deactivated := f.addUser(t, "left@university.edu", models.RoleStudent)
+	now := time.Now()
+	deactivated.DeactivatedAt = &now
+	f.store.Users().Update(ctx, &deactivated, "DeactivatedAt")
+	archived := f.course
+	archived.ArchivedAt = &now
+
+	refused := []struct {
+		name      string
+		course    models.Course
+		studentID uint
+		kind      Kind
+	}{
+		{"already enrolled", f.course, f.student.ID, KindInvalid},
+		{"not a student", f.course, f.teacher.ID, KindInvalid},
+		{"deactivated", f.course, deactivated.ID, KindInvalid},
+		{"archived course", archived, newcomer.ID, KindInvalid},
+		{"unknown student", f.course, 9999, KindNotFound},
+	}
+	for _, tc := range refused {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := enrollments.Enroll(ctx, tc.course, tc.studentID)
+			wantRefusal(t, err, tc.kind)
+		})
+	}
+}
+
+func TestEnrollMany(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	enrollments := NewEnrollmentService(f.store)
+
+	first := f.addUser(t, "first@university.edu", models.RoleStudent)
+	second := f.addUser(t, "second@university.edu", models.RoleStudent)
+	requested := []string{
+		strconv.Itoa(int(first.ID)),
+		"second@university.edu",
+		"first@university.edu", // Same student again, by email
+		strconv.Itoa(int(f.student.ID)),
+		strconv.Itoa(int(f.teacher.ID)),
+		"nobody@university.edu",
+	}
+	want := []string{EnrollStatusEnrolled, EnrollStatusEnrolled, EnrollStatusAlreadyEnrolled, EnrollStatusNotAStudent, EnrollStatusNotFound}
+
+	t.Run("all or nothing", func(t *testing.T) {
+		results, err := enrollments.EnrollMany(ctx, f.course, requested, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, result := range results {
+			if result.Status == EnrollStatusEnrolled {
+				t.Errorf("%s was enrolled although others failed", requested[result.Index])
+			}
+		}
+		if ids, _ := f.store.Enrollments().StudentIDs(ctx, f.course.ID); len(ids) != 1 {
+			t.Errorf("%d students are enrolled, want only the original one", len(ids))
+		}
+	})
+
+	results, err := enrollments.EnrollMany(ctx, f.course, requested, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		if result.Status != want[i] {
+			t.Errorf("%s: got %s, want %s", requested[result.Index], result.Status, want[i])
+		}
+	}
+	for _, student := range []models.User{first, second} {
+		if enrolled, _ := f.store.Enrollments().Exists(ctx, student.ID, f.course.ID); !enrolled {
+			t.Errorf("%s was not enrolled", student.Email)
+		}
+	}
+}
+
+func TestCohortNeedsCriteria(t *testing.T) {
+	_, err := NewEnrollmentService(newFixture(t).store).Cohort(context.Background(), "", 0)
+	wantRefusal(t, err, KindInvalid)
+}
@@ -0,0 +1,59 @@
+// Package services holds the business rules of grading, enrollment, user and
+// course management: saving grades and component scores with their history,
+// reading a course's roster and mark statistics, enrolling students one at a
+// time or in bulk, and the lifecycle of users and courses. Exports, analytics
+// and the other features still query the database from their controllers.
+// Services work on repositories only and know nothing about HTTP; controllers
+// translate their errors into responses.
+package services
+
+import (
+	"errors"
+	"fmt"
+	"grade-management-system/repository"
+)
+
+// Kind classifies why a service refused a request
+type Kind int
+
+const (
+	KindInvalid   Kind = iota + 1 // The request breaks a business rule
+	KindNotFound                  // A record the request refers to does not exist
+	KindForbidden                 // The caller may not act on the record
+	KindConflict                  // The request clashes with the current state
+)
+
+// Error is a refusal meant to be shown to the caller. Any other error returned
+// by a service is an unexpected failure.
+type Error struct {
+	Kind    Kind
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func invalid(format string, args ...interface{}) error {
+	return &Error{Kind: KindInvalid, Message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(message string) error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+func forbidden(message string) error {
+	return &Error{Kind: KindForbidden, Message: message}
+}
+
+func conflict(format string, args ...interface{}) error {
+	return &Error{Kind: KindConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// orNotFound turns a repository's ErrNotFound into a NotFound error with message
+func orNotFound(err error, message string) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return notFound(message)
+	}
+	return err
+}
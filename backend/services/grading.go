@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"grade-management-system/models"
+	"grade-management-system/repository"
+	"strconv"
+)
+
+// Actor identifies who made a change: a user or an API key
+type Actor struct {
+	Type             string
+	ID               uint // User ID or API key ID
+	ServiceAccountID *uint
+	ImpersonatorID   *uint // Admin behind an impersonated session
+}
+
+// GradeChange sets the final marks of a student in a course
+type GradeChange struct {
+	StudentID uint
+	CourseID  uint
+	Marks     float64
+}
+
+// ScoreChange sets a student's score in an assessment component of a course
+type ScoreChange struct {
+	CourseID    uint
+	ComponentID uint
+	StudentID   uint
+	Score       float64
+}
+
+// GradeObserver is told about marks once they are saved, for example to
+// record metrics or refresh early warnings. It runs on the request path, so it
+// must not block.
+type GradeObserver interface {
+	// GradesSaved is called after final grades of the course are saved
+	GradesSaved(courseID uint, studentIDs []uint)
+	// ScoreSaved is called after a component score of the course is saved
+	ScoreSaved(courseID uint, studentID uint)
+}
+
+// GradingService records final grades and component scores, and derives
+// letters and GPAs from them
+type GradingService interface {
+	// SaveGrade creates or updates the grade of an enrolled student and reports
+	// whether it was newly created
+	SaveGrade(ctx context.Context, change GradeChange, who Actor) (models.Grade, bool, error)
+	// SaveGrades saves several grades atomically. The students must already be
+	// known to be enrolled.
+	SaveGrades(ctx context.Context, changes []GradeChange, who Actor) error
+	// SaveScore creates or updates the component score of an enrolled student,
+	// attributed to the user who entered it
+	SaveScore(ctx context.Context, change ScoreChange, who Actor) (models.ComponentScore, error)
+	// StudentGrades returns the student's grades with their courses
+	StudentGrades(ctx context.Context, studentID uint) ([]models.Grade, error)
+	// GPA averages the grade points of the student's grades
+	GPA(ctx context.Context, studentID uint) (gpa float64, courses int, err error)
+	// CourseRoster returns the students enrolled in the course with their grades
+	CourseRoster(ctx context.Context, courseID uint) ([]repository.RosterEntry, error)
+	// CourseStatistics describes the marks of the course, with histograms of
+	// the given number of bins
+	CourseStatistics(ctx context.Context, courseID uint, bins int) (CourseStatistics, error)
+}
+
+// NewGradingService returns a GradingService. observer, if not nil, is told
+// about every grade and score saved.
+func NewGradingService(store repository.Store, observer GradeObserver) GradingService {
+	return &gradingService{store: store, observer: observer}
+}
+
+type gradingService struct {
+	store    repository.Store
+	observer GradeObserver
+}
+
+// GradeLetter converts marks out of 100 to a letter grade
+func GradeLetter(marks float64) string {
+	if marks >= 90 {
+		return "A"
+	} else if marks >= 80 {
+		return "B"
+	} else if marks >= 70 {
+		return "C"
+	} else if marks >= 60 {
+		return "D"
+	}
+	return "F"
+}
+
+// GradePoints returns the grade points of a letter grade: A=4, B=3, C=2, D=1, F=0
+func GradePoints(letter string) float64 {
+	switch letter {
+	case "A":
+		return 4
+	case "B":
+		return 3
+	case "C":
+		return 2
+	case "D":
+		return 1
+	}
+	return 0
+}
+
+// GradePointsSQL is GradePoints as a SQL expression over grades.grade_letter
+const GradePointsSQL = "CASE grades.grade_letter WHEN 'A' THEN 4 WHEN 'B' THEN 3 WHEN 'C' THEN 2 WHEN 'D' THEN 1 ELSE 0 END"
+
+func (s *gradingService) SaveGrade(ctx context.Context, change GradeChange, who Actor) (models.Grade, bool, error) {
+	enrolled, err := s.store.Enrollments().Exists(ctx, change.StudentID, change.CourseID)
+	if err != nil {
+		return models.Grade{}, false, err
+	}
+	if !enrolled {
+		return models.Grade{}, false, invalid("Student is not enrolled in this course")
+	}
+
+	var (
+		grade   models.Grade
+		created bool
+	)
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		var err error
+		grade, created, err = saveGrade(ctx, tx, change, who)
+		return err
+	})
+	if err != nil {
+		return grade, created, err
+	}
+
+	if s.observer != nil {
+		s.observer.GradesSaved(change.CourseID, []uint{change.StudentID})
+	}
+	return grade, created, nil
+}
+
+func (s *gradingService) SaveGrades(ctx context.Context, changes []GradeChange, who Actor) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	err := s.store.Transaction(ctx, func(tx repository.Store) error {
+		for _, change := range changes {
+			if _, _, err := saveGrade(ctx, tx, change, who); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.observer != nil {
+		perCourse := map[uint][]uint{}
+		for _, change := range changes {
+			perCourse[change.CourseID] = append(perCourse[change.CourseID], change.StudentID)
+		}
+		for courseID, studentIDs := range perCourse {
+			s.observer.GradesSaved(courseID, studentIDs)
+		}
+	}
+	return nil
+}
+
+// saveGrade writes one grade and its history entry within a transaction
+func saveGrade(ctx context.Context, tx repository.Store, change GradeChange, who Actor) (models.Grade, bool, error) {
+	history := models.GradeHistory{
+		CourseID:       change.CourseID,
+		StudentID:      change.StudentID,
+		NewValue:       change.Marks,
+		ActorType:      who.Type,
+		ActorID:        who.ID,
+		ImpersonatorID: who.ImpersonatorID,
+	}
+
+	created := false
+	grade, err := tx.Grades().Find(ctx, change.StudentID, change.CourseID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		grade = models.Grade{StudentID: change.StudentID, CourseID: change.CourseID}
+		created = true
+	case err != nil:
+		return grade, false, err
+	default:
+		oldMarks := grade.Marks
+		history.OldValue = &oldMarks
+	}
+
+	grade.Marks = change.Marks
+	grade.GradeLetter = GradeLetter(change.Marks)
+	if err := tx.Grades().Save(ctx, &grade); err != nil {
+		return grade, created, err
+	}
+	return grade, created, tx.Grades().AddHistory(ctx, &history)
+}
+
+func (s *gradingService) SaveScore(ctx context.Context, change ScoreChange, who Actor) (models.ComponentScore, error) {
+	component, err := s.store.Courses().FindComponent(ctx, change.CourseID, change.ComponentID)
+	if err != nil {
+		return models.ComponentScore{}, orNotFound(err, "Component not found in this course")
+	}
+	if change.Score > component.MaxScore {
+		return models.ComponentScore{}, invalid("Score exceeds the component's maximum of %s", strconv.FormatFloat(component.MaxScore, 'f', -1, 64))
+	}
+	enrolled, err := s.store.Enrollments().Exists(ctx, change.StudentID, change.CourseID)
+	if err != nil {
+		return models.ComponentScore{}, err
+	}
+	if !enrolled {
+		return models.ComponentScore{}, invalid("Student is not enrolled in this course")
+	}
+
+	var score models.ComponentScore
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		history := models.GradeHistory{
+			CourseID:       change.CourseID,
+			StudentID:      change.StudentID,
+			ComponentID:    &component.ID,
+			NewValue:       change.Score,
+			ActorType:      who.Type,
+			ActorID:        who.ID,
+			ImpersonatorID: who.ImpersonatorID,
+		}
+
+		var err error
+		score, err = tx.Grades().FindScore(ctx, component.ID, change.StudentID)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			score = models.ComponentScore{ComponentID: component.ID, StudentID: change.StudentID}
+		case err != nil:
+			return err
+		default:
+			oldScore := score.Score
+			history.OldValue = &oldScore
+		}
+
+		score.Score = change.Score
+		score.GradedByID = who.ID
+		if err := tx.Grades().SaveScore(ctx, &score); err != nil {
+			return err
+		}
+		return tx.Grades().AddHistory(ctx, &history)
+	})
+	if err != nil {
+		return score, err
+	}
+
+	if s.observer != nil {
+		s.observer.ScoreSaved(change.CourseID, change.StudentID)
+	}
+	return score, nil
+}
+
+func (s *gradingService) StudentGrades(ctx context.Context, studentID uint) ([]models.Grade, error) {
+	return s.store.Grades().ListByStudent(ctx, studentID)
+}
+
+func (s *gradingService) CourseRoster(ctx context.Context, courseID uint) ([]repository.RosterEntry, error) {
+	return s.store.Grades().Roster(ctx, courseID)
+}
+
+func (s *gradingService) GPA(ctx context.Context, studentID uint) (float64, int, error) {
+	grades, err := s.store.Grades().ListByStudent(ctx, studentID)
+	if err != nil || len(grades) == 0 {
+		return 0, 0, err
+	}
+
+	totalPoints := 0.0
+	for _, grade := range grades {
+		totalPoints += GradePoints(grade.GradeLetter)
+	}
+	return totalPoints / float64(len(grades)), len(grades), nil
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"grade-management-system/models"
+	"testing"
+)
+
+// recordingObserver remembers which students it was told about
+type recordingObserver struct {
+	grades map[uint][]uint // Students per course
+	scores []uint
+}
+
+func (o *recordingObserver) GradesSaved(courseID uint, studentIDs []uint) {
+	if o.grades == nil {
+		o.grades = map[uint][]uint{}
+	}
+	o.grades[courseID] = append(o.grades[courseID], studentIDs...)
+}
+
+func (o *recordingObserver) ScoreSaved(courseID, studentID uint) {
+	o.scores = append(o.scores, studentID)
+}
+
+func TestSaveGrade(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	observer := &recordingObserver{}
+	grading := NewGradingService(f.store, observer)
+	who := Actor{Type: models.ActorUser, ID: f.teacher.ID}
+
+	grade, created, err := grading.SaveGrade(ctx, GradeChange{StudentID: f.student.ID, CourseID: f.course.ID, Marks: 84}, who)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || grade.GradeLetter != "B" {
+		t.Errorf("got created=%v letter %q, want a new B", created, grade.GradeLetter)
+	}
+
+	grade, created, err = grading.SaveGrade(ctx, GradeChange{StudentID: f.student.ID, CourseID: f.course.ID, Marks: 91}, who)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || grade.GradeLetter != "A" {
+		t.Errorf("got created=%v letter %q, want an updated A", created, grade.GradeLetter)
+	}
+
+	history := f.store.History()
+	if len(history) != 2 {
+		t.Fatalf("%d history entries, want 2", len(history))
+	}
+	if history[0].OldValue != nil || history[1].OldValue == nil || *history[1].OldValue != 84 || history[1].NewValue != 91 {
+		t.Errorf("history does not record 84 -> 91: %+v", history)
+	}
+	if history[1].ActorID != f.teacher.ID || history[1].ComponentID != nil {
+		t.Errorf("history entry is attributed to %d for component %v", history[1].ActorID, history[1].ComponentID)
+	}
+	if got := observer.grades[f.course.ID]; len(got) != 2 || got[0] != f.student.ID {
+		t.Errorf("observer saw students %v, want the student twice", got)
+	}
+
+	gpa, courses, err := grading.GPA(ctx, f.student.ID)
+	if err != nil || gpa != 4 || courses != 1 {
+		t.Errorf("GPA = %v over %d courses (%v), want 4 over 1", gpa, courses, err)
+	}
+}
+
+func TestSaveGradeRequiresEnrollment(t *testing.T) {
+	f := newFixture(t)
+	outsider := f.addUser(t, "outsider@university.edu", models.RoleStudent)
+	observer := &recordingObserver{}
+
+	_, _, err := NewGradingService(f.store, observer).SaveGrade(context.Background(),
+		GradeChange{StudentID: outsider.ID, CourseID: f.course.ID, Marks: 70}, Actor{Type: models.ActorUser, ID: f.teacher.ID})
+	wantRefusal(t, err, KindInvalid)
+	if len(f.store.History()) != 0 || len(observer.grades) != 0 {
+		t.Error("a refused grade was recorded")
+	}
+}
+
+func TestSaveScore(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	observer := &recordingObserver{}
+	grading := NewGradingService(f.store, observer)
+	who := Actor{Type: models.ActorUser, ID: f.teacher.ID}
+
+	quiz := models.AssessmentComponent{CourseID: f.course.ID, Name: "Quiz", MaxScore: 20}
+	f.store.AddComponent(&quiz)
+	other := models.AssessmentComponent{CourseID: f.course.ID + 100, Name: "Elsewhere", MaxScore: 20}
+	f.store.AddComponent(&other)
+
+	t.Run("above the maximum", func(t *testing.T) {
+		_, err := grading.SaveScore(ctx, ScoreChange{CourseID: f.course.ID, ComponentID: quiz.ID, StudentID: f.student.ID, Score: 21}, who)
+		wantRefusal(t, err, KindInvalid)
+	})
+	t.Run("component of another course", func(t *testing.T) {
+		_, err := grading.SaveScore(ctx, ScoreChange{CourseID: f.course.ID, ComponentID: other.ID, StudentID: f.student.ID, Score: 10}, who)
+		wantRefusal(t, err, KindNotFound)
+	})
+	t.Run("student not enrolled", func(t *testing.T) {
+		outsider := f.addUser(t, "outsider@university.edu", models.RoleStudent)
+		_, err := grading.SaveScore(ctx, ScoreChange{CourseID: f.course.ID, ComponentID: quiz.ID, StudentID: outsider.ID, Score: 10}, who)
+		wantRefusal(t, err, KindInvalid)
+	})
+	if len(f.store.History()) != 0 || len(observer.scores) != 0 {
+		t.Fatal("a refused score was recorded")
+	}
+
+	for _, value := range []float64{12, 18} {
+		score, err := grading.SaveScore(ctx, ScoreChange{CourseID: f.course.ID, ComponentID: quiz.ID, StudentID: f.student.ID, Score: value}, who)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score.Score != value || score.GradedByID != f.teacher.ID {
+			t.Errorf("saved %v graded by %d, want %v by the teacher", score.Score, score.GradedByID, value)
+		}
+	}
+
+	history := f.store.History()
+	if len(history) != 2 || history[1].ComponentID == nil || *history[1].ComponentID != quiz.ID ||
+		history[1].OldValue == nil || *history[1].OldValue != 12 {
+		t.Errorf("history does not record the quiz going 12 -> 18: %+v", history)
+	}
+	if len(observer.scores) != 2 {
+		t.Errorf("observer saw %d scores, want 2", len(observer.scores))
+	}
+}
+
+func TestCourseStatistics(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	grading := NewGradingService(f.store, nil)
+
+	midterm := models.AssessmentComponent{CourseID: f.course.ID, Name: "Midterm", MaxScore: 50}
+	f.store.AddComponent(&midterm)
+	empty := models.AssessmentComponent{CourseID: f.course.ID, Name: "Final", MaxScore: 100}
+	f.store.AddComponent(&empty)
+
+	// The fixture's student stays ungraded
+	for i, marks := range []float64{55, 70, 82, 90, 100} {
+		student := f.addUser(t, fmt.Sprintf("student%d@university.edu", i), models.RoleStudent)
+		if err := f.store.Enrollments().Create(ctx, &models.Enrollment{StudentID: student.ID, CourseID: f.course.ID}); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.store.Grades().Save(ctx, &models.Grade{StudentID: student.ID, CourseID: f.course.ID, Marks: marks, GradeLetter: GradeLetter(marks)}); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.store.Grades().SaveScore(ctx, &models.ComponentScore{ComponentID: midterm.ID, StudentID: student.ID, Score: marks / 2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := grading.CourseStatistics(ctx, f.course.ID, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Enrolled != 6 || fmt.Sprint(stats.GradeLetters) != "[{A 2} {B 1} {C 1} {F 1}]" {
+		t.Errorf("enrolled %d, letters %v", stats.Enrolled, stats.GradeLetters)
+	}
+
+	marks := stats.Marks
+	if marks.Graded != 5 || marks.Ungraded != 1 || *marks.Mean != 79.4 || *marks.StdDev != 15.67 || *marks.Min != 55 || *marks.Max != 100 ||
+		*marks.Q1 != 70 || *marks.Median != 82 || *marks.Q3 != 90 || *marks.PassRate != 80 {
+		t.Errorf("marks: %+v", marks)
+	}
+	if bins := fmt.Sprint(marks.Histogram); bins != "[{0 25 0} {25 50 0} {50 75 2} {75 100 3}]" {
+		t.Errorf("histogram %s", bins)
+	}
+
+	if len(stats.Components) != 2 || stats.Components[0].ComponentID != midterm.ID {
+		t.Fatalf("components: %+v", stats.Components)
+	}
+	scores := stats.Components[0].Statistics
+	if scores.Graded != 5 || *scores.Mean != 39.7 || *scores.Median != 41 || *scores.PassRate != 80 ||
+		fmt.Sprint(scores.Histogram) != "[{0 12.5 0} {12.5 25 0} {25 37.5 2} {37.5 50 3}]" {
+		t.Errorf("midterm: %+v", scores)
+	}
+	if ungraded := stats.Components[1].Statistics; ungraded.Graded != 0 || ungraded.Ungraded != 6 || ungraded.Mean != nil || len(ungraded.Histogram) != 4 {
+		t.Errorf("component without scores: %+v", ungraded)
+	}
+}
+
+func TestCourseRoster(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	grading := NewGradingService(f.store, nil)
+
+	graded := f.addUser(t, "a-graded@university.edu", models.RoleStudent)
+	deleted := f.addUser(t, "deleted@university.edu", models.RoleStudent)
+	for _, student := range []models.User{graded, deleted} {
+		if err := f.store.Enrollments().Create(ctx, &models.Enrollment{StudentID: student.ID, CourseID: f.course.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := grading.SaveGrade(ctx, GradeChange{StudentID: graded.ID, CourseID: f.course.ID, Marks: 84}, Actor{Type: models.ActorUser, ID: f.teacher.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.store.Users().Delete(ctx, &deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	roster, err := grading.CourseRoster(ctx, f.course.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roster) != 2 {
+		t.Fatalf("roster: %+v", roster)
+	}
+	if first := roster[0]; first.StudentID != graded.ID || first.Marks == nil || *first.Marks != 84 || first.GradeLetter != "B" {
+		t.Errorf("graded student: %+v", first)
+	}
+	if second := roster[1]; second.StudentID != f.student.ID || second.Marks != nil || second.GradeLetter != "" {
+		t.Errorf("ungraded student: %+v", second)
+	}
+}
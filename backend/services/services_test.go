@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"grade-management-system/models"
+	"grade-management-system/repository"
+	"testing"
+)
+
+// fixture is a memory store with a teacher, a course and an enrolled student
+type fixture struct {
+	store   *repository.MemoryStore
+	teacher models.User
+	student models.User
+	course  models.Course
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	f := &fixture{store: repository.NewMemoryStore()}
+	f.teacher = f.addUser(t, "teacher@university.edu", models.RoleTeacher)
+	f.student = f.addUser(t, "student@university.edu", models.RoleStudent)
+
+	f.course = models.Course{Name: "Algorithms", Term: "2026-Spring", TeacherID: f.teacher.ID}
+	if err := f.store.Courses().Create(ctx, &f.course); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.store.Enrollments().Create(ctx, &models.Enrollment{StudentID: f.student.ID, CourseID: f.course.ID}); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func (f *fixture) addUser(t *testing.T, email, role string) models.User {
+	t.Helper()
+	user := models.User{Name: email, Email: email, Role: role}
+	if err := f.store.Users().Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// wantRefusal fails unless err is a services.Error of the given kind
+func wantRefusal(t *testing.T, err error, kind Kind) {
+	t.Helper()
+	var refusal *Error
+	if !errors.As(err, &refusal) || refusal.Kind != kind {
+		t.Fatalf("got error %v, want a refusal of kind %d", err, kind)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"grade-management-system/repository"
+	"math"
+)
+
+// PassMark is the lowest passing percentage, the lower bound of a D
+const PassMark = 60
+
+// MarkStatistics summarises a set of marks or scores. The figures are nil when
+// nothing has been graded yet.
+type MarkStatistics struct {
+	Graded    int64          `json:"graded"`
+	Ungraded  int64          `json:"ungraded"` // Enrolled students without a mark
+	Mean      *float64       `json:"mean"`
+	StdDev    *float64       `json:"std_dev"` // Population standard deviation
+	Min       *float64       `json:"min"`
+	Q1        *float64       `json:"q1"`
+	Median    *float64       `json:"median"`
+	Q3        *float64       `json:"q3"`
+	Max       *float64       `json:"max"`
+	PassRate  *float64       `json:"pass_rate"` // Percentage of graded students at or above the pass mark
+	Histogram []HistogramBin `json:"histogram"`
+}
+
+// HistogramBin counts the values from From up to, but excluding, To. The last
+// bin also includes its upper bound.
+type HistogramBin struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int64   `json:"count"`
+}
+
+// ComponentStatistics are the statistics of one assessment component, in its own score scale
+type ComponentStatistics struct {
+	ComponentID uint           `json:"component_id"`
+	Name        string         `json:"name"`
+	Weight      float64        `json:"weight"`
+	MaxScore    float64        `json:"max_score"`
+	Statistics  MarkStatistics `json:"statistics"`
+}
+
+// CourseStatistics is the distribution of the final marks of a course and of
+// the scores of each of its assessment components
+type CourseStatistics struct {
+	CourseID     uint                     `json:"course_id"`
+	Enrolled     int64                    `json:"enrolled"`
+	GradeLetters []repository.LetterCount `json:"grade_letters"`
+	Marks        MarkStatistics           `json:"marks"`
+	Components   []ComponentStatistics    `json:"components"`
+}
+
+// NewMarkStatistics rounds a summary into statistics. Enrolled students missing
+// from the summary count as ungraded.
+func NewMarkStatistics(summary repository.MarkSummary, enrolled int64, options repository.SummaryOptions) MarkStatistics {
+	stats := MarkStatistics{Graded: summary.Count, Ungraded: max(enrolled-summary.Count, 0)}
+	width := options.Max / float64(options.Bins)
+	stats.Histogram = make([]HistogramBin, options.Bins)
+	for i := range stats.Histogram {
+		stats.Histogram[i] = HistogramBin{From: roundTo(float64(i)*width, 2), To: roundTo(float64(i+1)*width, 2), Count: summary.Bins[i]}
+	}
+	if stats.Graded == 0 {
+		return stats
+	}
+
+	stdDev := math.Sqrt(math.Max(summary.MeanSquare-summary.Mean*summary.Mean, 0))
+	passRate := float64(summary.Passed) * 100 / float64(stats.Graded)
+	stats.Mean, stats.StdDev, stats.PassRate = roundedPtr(summary.Mean), roundedPtr(stdDev), roundedPtr(passRate)
+	minValue, maxValue := summary.Min, summary.Max
+	stats.Min, stats.Max = &minValue, &maxValue
+	stats.Q1, stats.Median, stats.Q3 = roundedPtr(summary.Quartiles[0]), roundedPtr(summary.Quartiles[1]), roundedPtr(summary.Quartiles[2])
+	return stats
+}
+
+func (s *gradingService) CourseStatistics(ctx context.Context, courseID uint, bins int) (CourseStatistics, error) {
+	stats := CourseStatistics{CourseID: courseID}
+	var err error
+	if stats.Enrolled, err = s.store.Enrollments().Count(ctx, courseID); err != nil {
+		return stats, err
+	}
+	if stats.GradeLetters, err = s.store.Grades().LetterCounts(ctx, courseID); err != nil {
+		return stats, err
+	}
+
+	options := repository.SummaryOptions{Max: 100, PassValue: PassMark, Bins: bins}
+	marks, err := s.store.Grades().SummarizeMarks(ctx, courseID, options)
+	if err != nil {
+		return stats, err
+	}
+	stats.Marks = NewMarkStatistics(marks, stats.Enrolled, options)
+
+	components, err := s.store.Courses().ListComponents(ctx, courseID)
+	if err != nil {
+		return stats, err
+	}
+	stats.Components = make([]ComponentStatistics, 0, len(components))
+	for _, component := range components {
+		options := repository.SummaryOptions{Max: component.MaxScore, PassValue: component.MaxScore * PassMark / 100, Bins: bins}
+		scores, err := s.store.Grades().SummarizeScores(ctx, component.ID, options)
+		if err != nil {
+			return stats, err
+		}
+		stats.Components = append(stats.Components, ComponentStatistics{
+			ComponentID: component.ID,
+			Name:        component.Name,
+			Weight:      component.Weight,
+			MaxScore:    component.MaxScore,
+			Statistics:  NewMarkStatistics(scores, stats.Enrolled, options),
+		})
+	}
+	return stats, nil
+}
+
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
+
+func roundedPtr(value float64) *float64 {
+	rounded := roundTo(value, 2)
+	return &rounded
+}
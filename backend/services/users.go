@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"grade-management-system/models"
+	"grade-management-system/repository"
+	"grade-management-system/utils"
+	"strings"
+	"time"
+)
+
+// NewUser holds the details of an account created by an admin
+type NewUser struct {
+	Name     string
+	Email    string
+	Password string
+	Role     string
+}
+
+// UserUpdate holds profile changes; nil fields are left as they are
+type UserUpdate struct {
+	Name  *string
+	Email *string
+	Role  *string
+}
+
+// UserService manages user accounts
+type UserService interface {
+	Find(ctx context.Context, id uint) (models.User, error)
+	Create(ctx context.Context, input NewUser) (models.User, error)
+	// Update applies the changes and returns a description of each one made
+	Update(ctx context.Context, user *models.User, update UserUpdate) ([]string, error)
+	// Deactivate blocks the user from logging in and reports whether anything changed.
+	// actingUserID is the admin making the request, who cannot deactivate themselves.
+	Deactivate(ctx context.Context, user *models.User, actingUserID uint) (bool, error)
+	// Reactivate lets the user log in again and reports whether anything changed
+	Reactivate(ctx context.Context, user *models.User) (bool, error)
+	// Delete soft-deletes the user, keeping the records that reference them
+	Delete(ctx context.Context, user *models.User, actingUserID uint) error
+}
+
+// NewUserService returns a UserService
+func NewUserService(store repository.Store) UserService {
+	return &userService{store: store}
+}
+
+type userService struct {
+	store repository.Store
+}
+
+func (s *userService) Find(ctx context.Context, id uint) (models.User, error) {
+	user, err := s.store.Users().FindByID(ctx, id)
+	return user, orNotFound(err, "User not found")
+}
+
+func (s *userService) Create(ctx context.Context, input NewUser) (models.User, error) {
+	email := strings.ToLower(input.Email)
+
+	// Deleted users keep their email, so they are included in the check
+	if err := s.checkEmailFree(ctx, email, 0); err != nil {
+		return models.User{}, err
+	}
+
+	hashedPassword, err := utils.HashPassword(input.Password)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		Name:     input.Name,
+		Email:    email,
+		Password: hashedPassword,
+		Role:     input.Role,
+	}
+	return user, s.store.Users().Create(ctx, &user)
+}
+
+func (s *userService) Update(ctx context.Context, user *models.User, update UserUpdate) ([]string, error) {
+	var changes []string
+	if update.Name != nil && *update.Name != user.Name {
+		user.Name = *update.Name
+		changes = append(changes, "name")
+	}
+
+	if update.Email != nil {
+		email := strings.ToLower(*update.Email)
+		if email != user.Email {
+			if err := s.checkEmailFree(ctx, email, user.ID); err != nil {
+				return nil, err
+			}
+			user.Email = email
+			changes = append(changes, "email")
+		}
+	}
+
+	if update.Role != nil && *update.Role != user.Role {
+		if user.Role == models.RoleAdmin {
+			return nil, forbidden("The role of an admin account cannot be changed")
+		}
+		if err := s.checkNoTaughtCourses(ctx, user, "changing their role"); err != nil {
+			return nil, err
+		}
+		changes = append(changes, fmt.Sprintf("role %s->%s", user.Role, *update.Role))
+		user.Role = *update.Role
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return changes, s.store.Users().Update(ctx, user, "Name", "Email", "Role")
+}
+
+func (s *userService) Deactivate(ctx context.Context, user *models.User, actingUserID uint) (bool, error) {
+	if user.ID == actingUserID {
+		return false, invalid("You cannot deactivate your own account")
+	}
+	if user.DeactivatedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	user.DeactivatedAt = &now
+	return true, s.store.Users().Update(ctx, user, "DeactivatedAt")
+}
+
+func (s *userService) Reactivate(ctx context.Context, user *models.User) (bool, error) {
+	if user.DeactivatedAt == nil {
+		return false, nil
+	}
+
+	user.DeactivatedAt = nil
+	return true, s.store.Users().Update(ctx, user, "DeactivatedAt")
+}
+
+func (s *userService) Delete(ctx context.Context, user *models.User, actingUserID uint) error {
+	if user.ID == actingUserID {
+		return invalid("You cannot delete your own account")
+	}
+	if err := s.checkNoTaughtCourses(ctx, user, "deleting them"); err != nil {
+		return err
+	}
+	return s.store.Users().Delete(ctx, user)
+}
+
+func (s *userService) checkEmailFree(ctx context.Context, email string, userID uint) error {
+	inUse, err := s.store.Users().EmailInUse(ctx, email, userID)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return conflict("Email already in use")
+	}
+	return nil
+}
+
+// checkNoTaughtCourses refuses the action while the user still teaches courses,
+// since those courses would be left without a teacher
+func (s *userService) checkNoTaughtCourses(ctx context.Context, user *models.User, action string) error {
+	taught, err := s.store.Courses().CountByTeacher(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if taught > 0 {
+		return conflict("The user still teaches %d course(s); reassign them before %s", taught, action)
+	}
+	return nil
+}
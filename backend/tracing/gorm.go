@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey stores a query's span on its statement between the callbacks
+const spanKey = "tracing:span"
+
+// GormPlugin creates a span for each query run with a context that is
+// already traced, e.g. config.DB.WithContext(c.Request.Context()). Queries
+// without one, such as background jobs, are not traced. The SQL is recorded
+// with placeholders, never with its arguments.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("tracing:create:before", startSpan("create")),
+		callback.Create().After("gorm:create").Register("tracing:create:after", endSpan),
+		callback.Query().Before("gorm:query").Register("tracing:query:before", startSpan("query")),
+		callback.Query().After("gorm:query").Register("tracing:query:after", endSpan),
+		callback.Update().Before("gorm:update").Register("tracing:update:before", startSpan("update")),
+		callback.Update().After("gorm:update").Register("tracing:update:after", endSpan),
+		callback.Delete().Before("gorm:delete").Register("tracing:delete:before", startSpan("delete")),
+		callback.Delete().After("gorm:delete").Register("tracing:delete:after", endSpan),
+		callback.Row().Before("gorm:row").Register("tracing:row:before", startSpan("row")),
+		callback.Row().After("gorm:row").Register("tracing:row:after", endSpan),
+		callback.Raw().Before("gorm:raw").Register("tracing:raw:before", startSpan("raw")),
+		callback.Raw().After("gorm:raw").Register("tracing:raw:after", endSpan),
+	)
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return
+		}
+
+		_, span := Tracer().Start(ctx, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNameKey.String(db.Dialector.Name()),
+				semconv.DBOperationName(operation),
+			),
+		)
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	if db.Statement.Table != "" {
+		span.SetAttributes(semconv.DBCollectionName(db.Statement.Table))
+	}
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
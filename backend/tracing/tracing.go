@@ -0,0 +1,91 @@
+// Package tracing sets up OpenTelemetry tracing. Spans are created for each
+// HTTP request, the auth middleware and each database query, and trace
+// context is propagated with the W3C traceparent and baggage headers.
+//
+// Tracing is off unless OTEL_TRACES_EXPORTER is set. While it is off the
+// global tracer provider is OpenTelemetry's no-op one and the HTTP and GORM
+// instrumentation is not installed, so it costs nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported values of OTEL_TRACES_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// ServiceName is reported unless OTEL_SERVICE_NAME overrides it
+const ServiceName = "grade-management-system"
+
+const instrumentationName = "grade-management-system"
+
+var enabled bool
+
+// Enabled reports whether Setup installed an exporter
+func Enabled() bool {
+	return enabled
+}
+
+// Tracer returns the tracer for the server's own spans
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup installs the tracer provider chosen by OTEL_TRACES_EXPORTER: none
+// (default), stdout to print spans as JSON, or otlp to send them over
+// OTLP/HTTP to OTEL_EXPORTER_OTLP_ENDPOINT. The returned function flushes
+// the spans still buffered and must be called before exiting.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch name := os.Getenv("OTEL_TRACES_EXPORTER"); name {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New()
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q, expected %s, %s or %s", name, ExporterNone, ExporterStdout, ExporterOTLP)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Later detectors win, so OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES
+	// override the default service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The sampler follows OTEL_TRACES_SAMPLER and defaults to sampling every
+	// trace not already sampled out by the caller
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	enabled = true
+	return provider.Shutdown, nil
+}
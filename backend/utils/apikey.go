@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix marks a credential as an API key rather than a JWT
+const APIKeyPrefix = "gms_"
+
+// GenerateAPIKey creates a new key of the form gms_<prefix>_<secret>.
+// It returns the full key, which is shown to the admin exactly once, the
+// lookup prefix and the hash that is stored.
+func GenerateAPIKey() (key, prefix, hash string) {
+	prefix = randomHex(6)
+	key = APIKeyPrefix + prefix + "_" + RandomToken(32)
+	return key, prefix, HashAPIKey(key)
+}
+
+// IsAPIKey reports whether a bearer credential looks like an API key
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
+
+// ParseAPIKeyPrefix extracts the lookup prefix from a full API key
+func ParseAPIKeyPrefix(key string) (string, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(key, APIKeyPrefix), "_", 2)
+	if !IsAPIKey(key) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// HashAPIKey hashes a key for storage. Keys carry 256 bits of entropy, so a
+// fast hash is sufficient and keeps per-request verification cheap.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckAPIKeyHash compares a presented key with a stored hash in constant time
+func CheckAPIKeyHash(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashAPIKey(key)), []byte(hash)) == 1
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTTLCacheIsCapped(t *testing.T) {
+	cache := NewTTLCache(time.Minute, 3)
+	for i := 0; i < 10; i++ {
+		cache.Set(fmt.Sprint(i), i)
+	}
+	if len(cache.entries) != 3 {
+		t.Fatalf("cache holds %d entries, want at most 3", len(cache.entries))
+	}
+	if _, ok := cache.Get("9"); !ok {
+		t.Error("the latest entry was evicted")
+	}
+
+	// Replacing a cached key does not evict another entry
+	cache.Set("9", 90)
+	if len(cache.entries) != 3 {
+		t.Errorf("cache holds %d entries after replacing one, want 3", len(cache.entries))
+	}
+}
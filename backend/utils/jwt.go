@@ -3,53 +3,95 @@ package utils
 import (
 	"errors"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// getJWTSecret fetches the secret from environment variables at runtime.
-// This ensures godotenv has already loaded variables from the .env file in main().
-func getJWTSecret() ([]byte, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return nil, errors.New("JWT_SECRET environment variable is not set in .env")
+const (
+	defaultIssuer   = "grade-management-system"
+	defaultAudience = "grade-management-api"
+	tokenLifetime   = 24 * time.Hour
+	// ImpersonationLifetime keeps "view as user" sessions short
+	ImpersonationLifetime = 15 * time.Minute
+)
+
+// getIssuer and getAudience are read at runtime so values from .env are honored
+func getIssuer() string {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return defaultIssuer
+}
+
+func getAudience() string {
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		return audience
 	}
-	return []byte(secret), nil
+	return defaultAudience
 }
 
-// Claims represents the JWT claims payload
+// ActorClaim identifies the real user behind an impersonation token (RFC 8693 "act")
+type ActorClaim struct {
+	UserID uint `json:"user_id"`
+}
+
+// Claims represents the JWT claims payload.
+// Impersonation tokens carry the impersonated user in UserID and the admin in Actor.
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Role   string `json:"role"`
+	UserID   uint        `json:"user_id"`
+	Role     string      `json:"role"`
+	Actor    *ActorClaim `json:"act,omitempty"`
+	ReadOnly bool        `json:"read_only,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT token for a given user ID and role
+// GenerateToken creates a new JWT token for a given user ID and role,
+// signed with the active key and tagged with its kid
 func GenerateToken(userID uint, role string) (string, error) {
-	secret, err := getJWTSecret()
+	return signToken(&Claims{UserID: userID, Role: role}, tokenLifetime)
+}
+
+// GenerateImpersonationToken creates a short-lived token that acts as the target
+// user while recording the admin who requested it
+func GenerateImpersonationToken(userID uint, role string, actorID uint, readOnly bool) (string, time.Time, error) {
+	claims := &Claims{
+		UserID:   userID,
+		Role:     role,
+		Actor:    &ActorClaim{UserID: actorID},
+		ReadOnly: readOnly,
+	}
+	token, err := signToken(claims, ImpersonationLifetime)
+	return token, claims.ExpiresAt.Time, err
+}
+
+// signToken fills in the registered claims and signs with the active key
+func signToken(claims *Claims, lifetime time.Duration) (string, error) {
+	ring, err := getKeyring()
 	if err != nil {
 		return "", err
 	}
 
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &Claims{
-		UserID: userID,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    getIssuer(),
+		Subject:   strconv.FormatUint(uint64(claims.UserID), 10),
+		Audience:  jwt.ClaimStrings{getAudience()},
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
+		ID:        randomHex(16),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(secret)
-
-	return tokenString, err
+	return ring.sign(claims)
 }
 
-// ValidateToken parses and validates a JWT token string
+// ValidateToken parses and validates a JWT token string against every
+// verification key in the keyring, so tokens signed with a retired key stay
+// valid until they expire
 func ValidateToken(signedToken string) (*Claims, error) {
-	secret, err := getJWTSecret()
+	ring, err := getKeyring()
 	if err != nil {
 		return nil, err
 	}
@@ -57,9 +99,12 @@ func ValidateToken(signedToken string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(
 		signedToken,
 		&Claims{},
-		func(token *jwt.Token) (interface{}, error) {
-			return secret, nil
-		},
+		ring.verificationKey,
+		jwt.WithValidMethods(signingAlgorithms),
+		jwt.WithIssuer(getIssuer()),
+		jwt.WithAudience(getAudience()),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
 	)
 
 	if err != nil {
@@ -71,5 +116,9 @@ func ValidateToken(signedToken string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	if claims.ID == "" || claims.Subject != strconv.FormatUint(uint64(claims.UserID), 10) {
+		return nil, errors.New("invalid token subject")
+	}
+
 	return claims, nil
 }
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestValidateToken(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "2026-04-01.pem", newEd25519Key(t))
+	useKeyDir(t, dir)
+	ring, err := getKeyring()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey := newEd25519Key(t)
+
+	now := time.Now()
+	valid := func() *Claims {
+		return &Claims{UserID: 7, Role: "teacher", RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    defaultIssuer,
+			Subject:   "7",
+			Audience:  jwt.ClaimStrings{defaultAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			ID:        "f3a9",
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Claims)
+		sign   func(*Claims) (string, error)
+		valid  bool
+	}{
+		{name: "valid", valid: true},
+		{name: "other issuer", modify: func(c *Claims) { c.Issuer = "someone-else" }},
+		{name: "no issuer", modify: func(c *Claims) { c.Issuer = "" }},
+		{name: "other audience", modify: func(c *Claims) { c.Audience = jwt.ClaimStrings{"another-api"} }},
+		{name: "no audience", modify: func(c *Claims) { c.Audience = nil }},
+		{name: "issued in the future", modify: func(c *Claims) { c.IssuedAt = jwt.NewNumericDate(now.Add(time.Hour)) }},
+		{name: "not yet valid", modify: func(c *Claims) { c.NotBefore = jwt.NewNumericDate(now.Add(time.Hour)) }},
+		{name: "expired", modify: func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute)) }},
+		{name: "no expiry", modify: func(c *Claims) { c.ExpiresAt = nil }},
+		{name: "no jti", modify: func(c *Claims) { c.ID = "" }},
+		{name: "subject is another user", modify: func(c *Claims) { c.Subject = "8" }},
+		{name: "no subject", modify: func(c *Claims) { c.Subject = "" }},
+		{
+			name: "unknown kid",
+			sign: func(c *Claims) (string, error) {
+				token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, c)
+				token.Header["kid"] = "2027-01-01"
+				return token.SignedString(otherKey)
+			},
+		},
+		{
+			name: "known kid, other key",
+			sign: func(c *Claims) (string, error) {
+				token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, c)
+				token.Header["kid"] = "2026-04-01"
+				return token.SignedString(otherKey)
+			},
+		},
+		{
+			name: "HMAC with the public key",
+			sign: func(c *Claims) (string, error) {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+				token.Header["kid"] = "2026-04-01"
+				return token.SignedString([]byte(ring.active.public.(ed25519.PublicKey)))
+			},
+		},
+		{
+			name: "unsigned",
+			sign: func(c *Claims) (string, error) {
+				token := jwt.NewWithClaims(jwt.SigningMethodNone, c)
+				token.Header["kid"] = "2026-04-01"
+				return token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			sign := func(c *Claims) (string, error) { return ring.sign(c) }
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+			if tt.sign != nil {
+				sign = tt.sign
+			}
+			token, err := sign(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ValidateToken(token)
+			if tt.valid {
+				if err != nil || got.UserID != 7 || got.Role != "teacher" {
+					t.Errorf("got %+v, %v; want user 7 as teacher", got, err)
+				}
+			} else if err == nil {
+				t.Error("token was accepted")
+			}
+		})
+	}
+}
+
+func TestGeneratedTokensRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "2026-04-01.pem", newEd25519Key(t))
+	useKeyDir(t, dir)
+	t.Setenv("JWT_ISSUER", "grades.example.edu")
+	t.Setenv("JWT_AUDIENCE", "grades-api")
+
+	token, err := GenerateToken(7, "teacher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Issuer != "grades.example.edu" || claims.Audience[0] != "grades-api" || claims.Actor != nil || claims.ReadOnly {
+		t.Errorf("login token claims: %+v", claims)
+	}
+	if lifetime := claims.ExpiresAt.Sub(claims.IssuedAt.Time); lifetime != tokenLifetime {
+		t.Errorf("login token lasts %v, want %v", lifetime, tokenLifetime)
+	}
+
+	again, _ := GenerateToken(7, "teacher")
+	if other, err := ValidateToken(again); err != nil || other.ID == claims.ID {
+		t.Errorf("two tokens share the jti %q (%v)", claims.ID, err)
+	}
+
+	impersonation, expires, err := GenerateImpersonationToken(9, "student", 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err = ValidateToken(impersonation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != 9 || claims.Actor == nil || claims.Actor.UserID != 1 || !claims.ReadOnly || !claims.ExpiresAt.Equal(expires) {
+		t.Errorf("impersonation token claims: %+v", claims)
+	}
+	if lifetime := expires.Sub(claims.IssuedAt.Time); lifetime != ImpersonationLifetime {
+		t.Errorf("impersonation token lasts %v, want %v", lifetime, ImpersonationLifetime)
+	}
+
+	// Tokens for another deployment's audience are rejected
+	t.Setenv("JWT_AUDIENCE", "")
+	if _, err := ValidateToken(token); err == nil || !strings.Contains(err.Error(), "audience") {
+		t.Errorf("token for another audience: %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is a single key in the keyring, identified by its kid.
+// Retired keys only carry the public half and are used for verification.
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// keyring holds the active signing key and every key accepted for verification.
+type keyring struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+var (
+	keyringMu  sync.Mutex
+	loadedKeys *keyring
+)
+
+// LoadSigningKeys reads the keys from JWT_KEY_DIR and makes them available for
+// signing and verification. The server calls it again on SIGHUP to pick up
+// rotated keys; when reading fails the keys already loaded stay in use.
+func LoadSigningKeys() error {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+
+	if loadedKeys != nil && os.Getenv("JWT_KEY_DIR") == "" {
+		// Replacing an ephemeral key would invalidate every token it signed
+		slog.Info("JWT_KEY_DIR is not set, keeping the ephemeral signing key")
+		return nil
+	}
+
+	ring, err := readKeyring()
+	if err != nil {
+		return err
+	}
+	loadedKeys = ring
+
+	slog.Info("JWT keyring loaded", "kid", ring.active.kid, "verification_keys", len(ring.keys))
+	return nil
+}
+
+// getKeyring returns the loaded keyring, loading it on first use.
+func getKeyring() (*keyring, error) {
+	keyringMu.Lock()
+	ring := loadedKeys
+	keyringMu.Unlock()
+	if ring != nil {
+		return ring, nil
+	}
+
+	if err := LoadSigningKeys(); err != nil {
+		return nil, err
+	}
+
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	return loadedKeys, nil
+}
+
+// readKeyring builds a keyring from the PEM files in JWT_KEY_DIR.
+// Each file is named after its kid: "<kid>.pem" holds a private key and
+// "<kid>.pub.pem" holds the public key of a retired key. Without JWT_KEY_DIR
+// an ephemeral Ed25519 key is generated, but only when JWT_EPHEMERAL_KEY=true
+// opts into it: its tokens do not survive a restart and are rejected by other
+// replicas, which only suits local development and tests.
+func readKeyring() (*keyring, error) {
+	dir := os.Getenv("JWT_KEY_DIR")
+	if dir == "" {
+		if os.Getenv("JWT_EPHEMERAL_KEY") != "true" {
+			return nil, errors.New("JWT_KEY_DIR is not set; set JWT_EPHEMERAL_KEY=true to sign with a throwaway key in development")
+		}
+		slog.Warn("JWT_KEY_DIR is not set, generating an ephemeral signing key for this process")
+		return ephemeralKeyring()
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	ring := &keyring{keys: make(map[string]*signingKey)}
+	for _, file := range files {
+		key, err := readKeyFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %s: %w", file, err)
+		}
+		if existing, ok := ring.keys[key.kid]; ok && existing.private != nil {
+			// A private key already provides the public half
+			continue
+		}
+		ring.keys[key.kid] = key
+	}
+
+	activeKID := os.Getenv("JWT_SIGNING_KID")
+	if activeKID == "" {
+		// Default to the newest key, assuming kids sort chronologically (e.g. 2026-10-01)
+		var kids []string
+		for kid, key := range ring.keys {
+			if key.private != nil {
+				kids = append(kids, kid)
+			}
+		}
+		if len(kids) == 0 {
+			return nil, fmt.Errorf("no private signing key found in %s", dir)
+		}
+		sort.Strings(kids)
+		activeKID = kids[len(kids)-1]
+	}
+
+	active, ok := ring.keys[activeKID]
+	if !ok || active.private == nil {
+		return nil, fmt.Errorf("signing key %q not found in %s", activeKID, dir)
+	}
+	ring.active = active
+
+	return ring, nil
+}
+
+func ephemeralKeyring() (*keyring, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{
+		kid:     "ephemeral-" + randomHex(4),
+		method:  jwt.SigningMethodEdDSA,
+		private: private,
+		public:  public,
+	}
+	return &keyring{active: key, keys: map[string]*signingKey{key.kid: key}}, nil
+}
+
+// readKeyFile parses a single PEM encoded RSA or Ed25519 key
+func readKeyFile(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	kid := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".pem"), ".pub")
+	key := &signingKey{kid: kid}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+		key.private = signer
+		key.public = signer.Public()
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.private = parsed
+		key.public = parsed.Public()
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.public = parsed
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+
+	switch key.public.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+
+	return key, nil
+}
+
+// JSONWebKey is the public representation of a verification key (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet is the document published at /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// PublicJWKS returns every verification key so other services can validate tokens
+func PublicJWKS() (JSONWebKeySet, error) {
+	ring, err := getKeyring()
+	if err != nil {
+		return JSONWebKeySet{}, err
+	}
+
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range ring.keys {
+		jwk := JSONWebKey{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set, nil
+}
+
+// sign serializes the claims with the active key, tagging the token with its kid
+func (r *keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(r.active.method, claims)
+	token.Header["kid"] = r.active.kid
+	return token.SignedString(r.active.private)
+}
+
+// verificationKey is the jwt.Keyfunc that picks the key named by the token's kid
+func (r *keyring) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.public, nil
+}
+
+// signingAlgorithms lists the only algorithms accepted when parsing tokens
+var signingAlgorithms = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
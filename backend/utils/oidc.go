@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// oidcSessionAudience keeps login session tokens from being accepted as access tokens
+const oidcSessionAudience = "oidc-session"
+
+// OIDCSessionLifetime bounds how long a user may take to log in at the identity provider
+const OIDCSessionLifetime = 10 * time.Minute
+
+// OIDCSettings holds the identity provider configuration read from the environment
+type OIDCSettings struct {
+	IssuerURL       string
+	ClientID        string
+	ClientSecret    string
+	RedirectURL     string
+	Scopes          []string
+	EmailClaim      string
+	RequireVerified bool
+	AutoProvision   bool
+	// ProvisionDomains restricts auto-provisioning to these email domains (empty allows any)
+	ProvisionDomains []string
+}
+
+// OIDCProvider wraps the discovered provider metadata and the OAuth2 client
+type OIDCProvider struct {
+	Settings OIDCSettings
+	OAuth2   oauth2.Config
+	Verifier *oidc.IDTokenVerifier
+}
+
+var (
+	oidcMu       sync.Mutex
+	oidcProvider *OIDCProvider
+)
+
+// GetOIDCSettings reads the OIDC settings at runtime.
+// SSO is disabled unless OIDC_ISSUER_URL and OIDC_CLIENT_ID are set.
+func GetOIDCSettings() (OIDCSettings, bool) {
+	settings := OIDCSettings{
+		IssuerURL:       os.Getenv("OIDC_ISSUER_URL"),
+		ClientID:        os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:    os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:     os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:          strings.Fields(os.Getenv("OIDC_SCOPES")),
+		EmailClaim:      os.Getenv("OIDC_EMAIL_CLAIM"),
+		RequireVerified: os.Getenv("OIDC_REQUIRE_VERIFIED_EMAIL") != "false",
+		AutoProvision:   os.Getenv("OIDC_AUTO_PROVISION") == "true",
+	}
+	if settings.IssuerURL == "" || settings.ClientID == "" {
+		return settings, false
+	}
+
+	if len(settings.Scopes) == 0 {
+		settings.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	if settings.EmailClaim == "" {
+		settings.EmailClaim = "email"
+	}
+	for _, domain := range strings.Split(os.Getenv("OIDC_PROVISION_DOMAINS"), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			settings.ProvisionDomains = append(settings.ProvisionDomains, domain)
+		}
+	}
+
+	return settings, true
+}
+
+// GetOIDCProvider returns the configured provider, running discovery on first use.
+// A failed discovery is retried on the next call so a briefly unavailable IdP
+// does not require a restart.
+func GetOIDCProvider(ctx context.Context) (*OIDCProvider, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+
+	if oidcProvider != nil {
+		return oidcProvider, nil
+	}
+
+	settings, enabled := GetOIDCSettings()
+	if !enabled {
+		return nil, errors.New("OIDC is not configured")
+	}
+
+	provider, err := oidc.NewProvider(ctx, settings.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	oidcProvider = &OIDCProvider{
+		Settings: settings,
+		OAuth2: oauth2.Config{
+			ClientID:     settings.ClientID,
+			ClientSecret: settings.ClientSecret,
+			RedirectURL:  settings.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       settings.Scopes,
+		},
+		Verifier: provider.Verifier(&oidc.Config{ClientID: settings.ClientID}),
+	}
+	return oidcProvider, nil
+}
+
+// CanProvision reports whether an unknown email may be auto-provisioned as a student
+func (s OIDCSettings) CanProvision(email string) bool {
+	if !s.AutoProvision {
+		return false
+	}
+	if len(s.ProvisionDomains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range s.ProvisionDomains {
+		if domain == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// OIDCSession carries the per-login secrets between the redirect to the IdP
+// and the callback. It is signed with the JWT keyring and stored in a cookie,
+// so any replica can complete the login.
+type OIDCSession struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// NewOIDCSession generates a fresh state, nonce and PKCE verifier
+func NewOIDCSession() *OIDCSession {
+	now := time.Now()
+	return &OIDCSession{
+		State:    randomHex(16),
+		Nonce:    randomHex(16),
+		Verifier: oauth2.GenerateVerifier(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    getIssuer(),
+			Audience:  jwt.ClaimStrings{oidcSessionAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(OIDCSessionLifetime)),
+		},
+	}
+}
+
+// SignOIDCSession serializes the session into a signed token for the cookie
+func SignOIDCSession(session *OIDCSession) (string, error) {
+	ring, err := getKeyring()
+	if err != nil {
+		return "", err
+	}
+
+	return ring.sign(session)
+}
+
+// ParseOIDCSession validates the cookie token and returns the session
+func ParseOIDCSession(signed string) (*OIDCSession, error) {
+	ring, err := getKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.ParseWithClaims(
+		signed,
+		&OIDCSession{},
+		ring.verificationKey,
+		jwt.WithValidMethods(signingAlgorithms),
+		jwt.WithIssuer(getIssuer()),
+		jwt.WithAudience(oidcSessionAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	session, ok := token.Claims.(*OIDCSession)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid login session")
+	}
+	return session, nil
+}
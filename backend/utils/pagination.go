@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Page sizes accepted by list endpoints
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// ListOptions are the validated pagination, sorting and search parameters of a list request
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// Keyset pagination, requested with ?paging=cursor or by passing a cursor:
+	// pages follow the ID order and Cursor is the last ID already seen
+	UseCursor bool
+	Cursor    uint
+	Sort      string // Column to order by
+	Desc      bool
+	Search    string
+}
+
+// Pagination describes the returned page so clients can render page numbers
+// and follow the next/prev links
+type Pagination struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor *uint  `json:"next_cursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+}
+
+// ParseListOptions reads ?limit=, ?offset=, ?paging=, ?cursor=, ?sort= and ?q=
+// from the request. sortable maps the sort keys a client may use to their
+// columns; prefixing the key with "-" sorts descending. Cursor paging follows
+// the ID and cannot be combined with an offset or another sort key.
+func ParseListOptions(c *gin.Context, sortable map[string]string, defaultSort string) (ListOptions, error) {
+	opts := ListOptions{Limit: DefaultPageSize, Search: strings.TrimSpace(c.Query("q"))}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxPageSize {
+			return opts, fmt.Errorf("limit must be between 1 and %d", MaxPageSize)
+		}
+		opts.Limit = limit
+	}
+
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return opts, errors.New("offset must be a non-negative integer")
+		}
+		opts.Offset = offset
+	}
+
+	sortKey := c.DefaultQuery("sort", defaultSort)
+	if strings.HasPrefix(sortKey, "-") {
+		opts.Desc = true
+		sortKey = sortKey[1:]
+	}
+	column, ok := sortable[sortKey]
+	if !ok {
+		keys := make([]string, 0, len(sortable))
+		for key := range sortable {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return opts, fmt.Errorf("sort must be one of %s (prefix with - for descending)", strings.Join(keys, ", "))
+	}
+	opts.Sort = column
+
+	opts.UseCursor = c.Query("paging") == "cursor" || c.Query("cursor") != ""
+	if opts.UseCursor {
+		if opts.Offset != 0 || sortKey != "id" {
+			return opts, errors.New("cursor paging cannot be combined with offset or a sort other than id")
+		}
+		if value := c.Query("cursor"); value != "" {
+			cursor, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || cursor == 0 {
+				return opts, errors.New("cursor must be a positive integer")
+			}
+			opts.Cursor = uint(cursor)
+		}
+	}
+	return opts, nil
+}
+
+// LikePattern turns a search term into a case-insensitive LIKE pattern matching
+// it anywhere. Use it with LOWER(column) LIKE ? ESCAPE '\'.
+func LikePattern(search string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(search))
+	return "%" + escaped + "%"
+}
+
+// Paginate counts the rows matched by query, then loads the requested page.
+// idColumn is the qualified ID column used for cursors and as a tie-breaker so
+// pages are stable; idOf extracts the ID from a loaded row.
+func Paginate[T any](c *gin.Context, query *gorm.DB, opts ListOptions, idColumn string, idOf func(T) uint) ([]T, Pagination, error) {
+	page := Pagination{Limit: opts.Limit, Offset: opts.Offset}
+	if err := query.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return nil, page, err
+	}
+
+	direction := "ASC"
+	if opts.Desc {
+		direction = "DESC"
+	}
+	query = query.Order(opts.Sort + " " + direction)
+	if opts.Sort != idColumn {
+		query = query.Order(idColumn + " " + direction)
+	}
+
+	switch {
+	case !opts.UseCursor:
+		query = query.Offset(opts.Offset)
+	case opts.Cursor == 0:
+		// First page of a cursor walk
+	case opts.Desc:
+		query = query.Where(idColumn+" < ?", opts.Cursor)
+	default:
+		query = query.Where(idColumn+" > ?", opts.Cursor)
+	}
+
+	items := make([]T, 0, opts.Limit)
+	if err := query.Limit(opts.Limit).Find(&items).Error; err != nil {
+		return nil, page, err
+	}
+
+	if opts.UseCursor {
+		if len(items) == opts.Limit {
+			next := idOf(items[len(items)-1])
+			page.NextCursor = &next
+			page.Next = pageURL(c, map[string]string{"cursor": strconv.FormatUint(uint64(next), 10), "offset": ""})
+		}
+		return items, page, nil
+	}
+
+	if int64(opts.Offset+len(items)) < page.Total {
+		page.Next = pageURL(c, map[string]string{"offset": strconv.Itoa(opts.Offset + opts.Limit)})
+	}
+	if opts.Offset > 0 {
+		prev := opts.Offset - opts.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		page.Prev = pageURL(c, map[string]string{"offset": strconv.Itoa(prev)})
+	}
+	return items, page, nil
+}
+
+// PaginatedResponse writes a list page. The items stay under "data" as in
+// SuccessResponse, with the page details alongside.
+func PaginatedResponse(c *gin.Context, message string, data interface{}, page Pagination) {
+	c.JSON(http.StatusOK, gin.H{
+		"message":    message,
+		"data":       data,
+		"pagination": page,
+	})
+}
+
+// pageURL returns the current request path and query with some parameters
+// replaced; an empty value removes the parameter
+func pageURL(c *gin.Context, params map[string]string) string {
+	query := c.Request.URL.Query()
+	for key, value := range params {
+		if value == "" {
+			query.Del(key)
+		} else {
+			query.Set(key, value)
+		}
+	}
+	return c.Request.URL.Path + "?" + query.Encode()
+}
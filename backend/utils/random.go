@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// randomBytes returns n cryptographically random bytes
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand failed: " + err.Error())
+	}
+	return b
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	return hex.EncodeToString(randomBytes(n))
+}
+
+// RandomToken returns n random bytes encoded as URL-safe base64, suitable for
+// unguessable secrets such as generated passwords
+func RandomToken(n int) string {
+	return base64.RawURLEncoding.EncodeToString(randomBytes(n))
+}
@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Spreadsheet formats accepted for uploads and offered for downloads
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// MaxUploadRows caps the number of data rows accepted in one uploaded file
+const MaxUploadRows = 5000
+
+// Sheet is a parsed upload: a normalized header and the data rows below it.
+// Line holds the 1-based line number of each row in the original file.
+type Sheet struct {
+	Header []string
+	Rows   [][]string
+	Line   []int
+}
+
+// Column returns the index of a header column, or -1 if it is missing
+func (s *Sheet) Column(name string) int {
+	for i, column := range s.Header {
+		if column == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Cell returns the trimmed value of a column in a row, or "" when the column or cell is missing
+func (s *Sheet) Cell(row []string, column int) string {
+	if column < 0 || column >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[column])
+}
+
+// ReadCSV parses an uploaded CSV file. The first line is the header; its
+// column names are lower-cased and trimmed so they can be matched loosely.
+// Blank lines are skipped.
+func ReadCSV(r io.Reader) (*Sheet, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := &Sheet{Header: normalizeHeader(header)}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if !appendRow(sheet, record, line) {
+			return nil, errors.New("file has too many rows")
+		}
+	}
+	return sheet, nil
+}
+
+// ReadXLSX parses the first worksheet of an uploaded XLSX file the same way as ReadCSV
+func ReadXLSX(r io.Reader) (*Sheet, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rows, err := f.Rows(f.GetSheetName(0))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sheet *Sheet
+	for line := 1; rows.Next(); line++ {
+		record, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+		if sheet == nil {
+			sheet = &Sheet{Header: normalizeHeader(record)}
+			continue
+		}
+		if !appendRow(sheet, record, line) {
+			return nil, errors.New("file has too many rows")
+		}
+	}
+	if sheet == nil {
+		return nil, errors.New("file is empty")
+	}
+	return sheet, rows.Error()
+}
+
+// ReadUpload parses an uploaded CSV or XLSX file, chosen by its extension
+func ReadUpload(fileHeader *multipart.FileHeader) (*Sheet, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+	if format != FormatCSV && format != FormatXLSX {
+		return nil, errors.New("unsupported file type, upload a .csv or .xlsx file")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if format == FormatXLSX {
+		return ReadXLSX(file)
+	}
+	return ReadCSV(file)
+}
+
+// appendRow adds a non-blank row to the sheet, returning false once MaxUploadRows is exceeded
+func appendRow(sheet *Sheet, record []string, line int) bool {
+	blank := true
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			blank = false
+			break
+		}
+	}
+	if blank {
+		return true
+	}
+
+	if len(sheet.Rows) >= MaxUploadRows {
+		return false
+	}
+	sheet.Rows = append(sheet.Rows, record)
+	sheet.Line = append(sheet.Line, line)
+	return true
+}
+
+func normalizeHeader(header []string) []string {
+	normalized := make([]string, len(header))
+	for i, column := range header {
+		column = strings.TrimPrefix(column, "\ufeff") // Byte order mark written by Excel
+		normalized[i] = strings.ToLower(strings.TrimSpace(column))
+	}
+	return normalized
+}
+
+// SheetWriter writes rows of a downloadable CSV or XLSX file. Rows are streamed
+// to the underlying writer (CSV) or to disk-backed storage (XLSX) instead of
+// being held in memory; Close must be called to finish the file.
+type SheetWriter interface {
+	WriteRow(cells ...interface{}) error
+	Close() error
+}
+
+// NewSheetWriter returns a SheetWriter for the given format
+func NewSheetWriter(w io.Writer, format string) (SheetWriter, error) {
+	switch format {
+	case FormatCSV:
+		return &csvSheetWriter{w: csv.NewWriter(w)}, nil
+	case FormatXLSX:
+		f := excelize.NewFile()
+		stream, err := f.NewStreamWriter(f.GetSheetName(0))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &xlsxSheetWriter{out: w, file: f, stream: stream}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// SheetContentType returns the MIME type of a spreadsheet format
+func SheetContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// formulaPrefixes start a formula (or, for tab and CR, let a spreadsheet
+// shift the cell into one) when a cell is opened in Excel or LibreOffice
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeCell keeps text cells from being interpreted as formulas, so a name
+// like =HYPERLINK(...) entered at registration stays text in an export.
+// Numbers are written as they are, which leaves negative values intact.
+func escapeCell(cell interface{}) interface{} {
+	var text string
+	switch value := cell.(type) {
+	case string:
+		text = value
+	case *string:
+		if value == nil {
+			return nil
+		}
+		text = *value
+	default:
+		return cell
+	}
+	if text != "" && strings.ContainsRune(formulaPrefixes, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+type csvSheetWriter struct {
+	w    *csv.Writer
+	rows int
+}
+
+func (s *csvSheetWriter) WriteRow(cells ...interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		if cell = escapeCell(cell); cell != nil {
+			record[i] = fmt.Sprint(cell)
+		}
+	}
+	if err := s.w.Write(record); err != nil {
+		return err
+	}
+
+	// Flush regularly so large files reach the client as they are produced
+	s.rows++
+	if s.rows%500 == 0 {
+		s.w.Flush()
+	}
+	return s.w.Error()
+}
+
+func (s *csvSheetWriter) Close() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+type xlsxSheetWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	rows   int
+}
+
+func (s *xlsxSheetWriter) WriteRow(cells ...interface{}) error {
+	s.rows++
+	cell, err := excelize.CoordinatesToCellName(1, s.rows)
+	if err != nil {
+		return err
+	}
+	escaped := make([]interface{}, len(cells))
+	for i, value := range cells {
+		escaped[i] = escapeCell(value)
+	}
+	return s.stream.SetRow(cell, escaped)
+}
+
+func (s *xlsxSheetWriter) Close() error {
+	defer s.file.Close()
+	if err := s.stream.Flush(); err != nil {
+		return err
+	}
+	return s.file.Write(s.out)
+}